@@ -1,9 +1,16 @@
 package main
 
 import (
+	"cmp"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"simarena/internal/api"
 	"simarena/internal/llm"
@@ -16,10 +23,34 @@ func main() {
 	// Configuration from env or defaults
 	port := getEnv("PORT", "8080")
 	corsOrigin := getEnv("CORS_ORIGIN", "http://localhost:5173")
-	llmBaseURL := getEnv("LLM_BASE_URL", "http://localhost:7090/v1")
+	llmProvider := getEnv("LLM_PROVIDER", "openai")
+	llmBaseURL := getEnv("LLM_BASE_URL", defaultBaseURL(llmProvider))
+	if strings.HasPrefix(llmBaseURL, llm.MockScheme) {
+		llmProvider = "mock"
+	}
 	llmModel := getEnv("LLM_MODEL", "openai/gpt-oss-20b")
 	llmAPIKey := getEnv("LLM_API_KEY", "not-needed")
+	llmResponseFormat := getEnv("LLM_RESPONSE_FORMAT", "false") == "true"
 	dataPath := getEnv("DATA_PATH", "./data")
+	maxConcurrent, err := strconv.Atoi(getEnv("MAX_CONCURRENT_SIMULATIONS", "2"))
+	if err != nil {
+		log.Fatalf("Invalid MAX_CONCURRENT_SIMULATIONS: %v", err)
+	}
+	retry := llm.DefaultRetryPolicy()
+	if retry.MaxAttempts, err = strconv.Atoi(getEnv("LLM_RETRY_ATTEMPTS", strconv.Itoa(retry.MaxAttempts))); err != nil {
+		log.Fatalf("Invalid LLM_RETRY_ATTEMPTS: %v", err)
+	}
+	if retry.BaseDelay, err = time.ParseDuration(getEnv("LLM_RETRY_BASE_DELAY", retry.BaseDelay.String())); err != nil {
+		log.Fatalf("Invalid LLM_RETRY_BASE_DELAY: %v", err)
+	}
+	if retry.MaxDelay, err = time.ParseDuration(getEnv("LLM_RETRY_MAX_DELAY", retry.MaxDelay.String())); err != nil {
+		log.Fatalf("Invalid LLM_RETRY_MAX_DELAY: %v", err)
+	}
+
+	limiter, err := newRateLimiter()
+	if err != nil {
+		log.Fatalf("Invalid rate limits: %v", err)
+	}
 
 	// Storage
 	store, err := storage.NewJSONStore(dataPath)
@@ -32,29 +63,192 @@ func main() {
 	llmCfg.BaseURL = llmBaseURL
 	llmCfg.Model = llmModel
 	llmCfg.APIKey = llmAPIKey
-	llmClient := llm.NewClient(llmCfg)
+	llmCfg.ResponseFormat = llmResponseFormat
+	llmCfg.Retry = retry
+	llmCfg.Limiter = limiter
+	var provider llm.Provider
+	var pool *llm.Pool
+	if endpoints := os.Getenv("LLM_ENDPOINTS"); endpoints != "" {
+		pool, err = newPool(endpoints, llmProvider, llmCfg)
+		if err != nil {
+			log.Fatalf("Invalid LLM_ENDPOINTS: %v", err)
+		}
+		provider = pool
+	} else if provider, err = newProvider(llmProvider, llmCfg); err != nil {
+		log.Fatalf("Failed to create LLM provider: %v", err)
+	}
+	if getEnv("LLM_CACHE", "false") == "true" {
+		cache, err := newCache(filepath.Join(dataPath, "llm-cache"), llmModel)
+		if err != nil {
+			log.Fatalf("Failed to create LLM cache: %v", err)
+		}
+		provider = llm.WithCache(provider, cache)
+	}
 
 	// WebSocket hub
 	hub := api.NewHub()
 
 	// Simulation engine
-	engine := simulation.NewEngine(llmClient, store, func(simID string, step models.Step) {
+	engine := simulation.NewEngine(provider, store, maxConcurrent, func(simID string, step models.Step) {
 		hub.BroadcastStep(simID, step)
 	})
 
 	// HTTP handler and router
-	handler := api.NewHandler(store, engine, hub)
+	handler := api.NewHandler(store, engine, hub, limiter, pool)
 	router := api.NewRouter(handler, corsOrigin)
 
 	log.Printf("SimArena backend starting on :%s", port)
 	log.Printf("CORS origin: %s", corsOrigin)
-	log.Printf("LLM endpoint: %s (model: %s)", llmBaseURL, llmModel)
+	if pool != nil {
+		for _, ep := range pool.Status() {
+			log.Printf("LLM endpoint: %s (pooled, model: %s)", ep.Name, llmModel)
+		}
+	} else {
+		log.Printf("LLM endpoint: %s (provider: %s, model: %s)", llmBaseURL, llmProvider, llmModel)
+	}
+	log.Printf("Max concurrent simulations: %d", maxConcurrent)
 
 	if err := http.ListenAndServe(":"+port, router); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
 
+// defaultBaseURL returns the endpoint used for a provider when LLM_BASE_URL is not set.
+func defaultBaseURL(provider string) string {
+	switch provider {
+	case "anthropic":
+		return llm.DefaultAnthropicBaseURL
+	case "ollama":
+		return llm.DefaultOllamaBaseURL
+	case "mock":
+		return llm.MockScheme
+	}
+	return "http://localhost:7090/v1"
+}
+
+// newProvider creates the provider of the given kind with cfg.
+func newProvider(kind string, cfg llm.Config) (llm.Provider, error) {
+	if strings.HasPrefix(cfg.BaseURL, llm.MockScheme) {
+		kind = "mock"
+	}
+	switch kind {
+	case "openai":
+		return llm.NewClient(cfg), nil
+	case "anthropic":
+		return llm.NewAnthropic(cfg), nil
+	case "ollama":
+		return llm.NewOllama(cfg), nil
+	case "mock":
+		return newMock(strings.TrimPrefix(cfg.BaseURL, llm.MockScheme))
+	}
+	return nil, fmt.Errorf("unknown provider %q (want openai, anthropic, ollama or mock)", kind)
+}
+
+// endpointConfig is one entry of LLM_ENDPOINTS. Empty fields take the values of
+// LLM_PROVIDER and LLM_API_KEY.
+type endpointConfig struct {
+	Provider string   `json:"provider"`
+	BaseURL  string   `json:"base_url"`
+	APIKey   string   `json:"api_key"`
+	Models   []string `json:"models"`
+}
+
+// newPool creates a pool over the endpoints in the JSON list spec, balanced by
+// LLM_BALANCE and ejecting endpoints after LLM_EJECT_AFTER consecutive failures for
+// LLM_EJECT_COOLDOWN. Each endpoint makes a single attempt per request; cfg.Retry
+// applies to the pool as a whole, so a failing endpoint hands over at once.
+func newPool(spec, defaultProvider string, cfg llm.Config) (*llm.Pool, error) {
+	var endpoints []endpointConfig
+	if err := json.Unmarshal([]byte(spec), &endpoints); err != nil {
+		return nil, err
+	}
+	poolCfg := llm.DefaultPoolConfig()
+	poolCfg.Model = cfg.Model
+	poolCfg.Retry = cfg.Retry
+	poolCfg.Balance = getEnv("LLM_BALANCE", poolCfg.Balance)
+	var err error
+	if poolCfg.FailureThreshold, err = strconv.Atoi(getEnv("LLM_EJECT_AFTER", strconv.Itoa(poolCfg.FailureThreshold))); err != nil {
+		return nil, fmt.Errorf("LLM_EJECT_AFTER: %w", err)
+	}
+	if poolCfg.Cooldown, err = time.ParseDuration(getEnv("LLM_EJECT_COOLDOWN", poolCfg.Cooldown.String())); err != nil {
+		return nil, fmt.Errorf("LLM_EJECT_COOLDOWN: %w", err)
+	}
+
+	members := make([]llm.PoolEndpoint, 0, len(endpoints))
+	for i, ep := range endpoints {
+		kind := cmp.Or(ep.Provider, defaultProvider)
+		epCfg := cfg
+		epCfg.BaseURL = cmp.Or(ep.BaseURL, defaultBaseURL(kind))
+		epCfg.APIKey = cmp.Or(ep.APIKey, cfg.APIKey)
+		epCfg.Retry.MaxAttempts = 1
+		provider, err := newProvider(kind, epCfg)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %d: %w", i+1, err)
+		}
+		members = append(members, llm.PoolEndpoint{Name: epCfg.BaseURL, Provider: provider, Models: ep.Models})
+	}
+	return llm.NewPool(poolCfg, members)
+}
+
+// newCache opens the LLM response cache in dir, configured by LLM_CACHE_TTL,
+// LLM_CACHE_MAX_MB, LLM_CACHE_FORCE and LLM_CACHE_STREAM_DELAY.
+func newCache(dir, model string) (*llm.Cache, error) {
+	cfg := llm.CacheConfig{Model: model, Force: getEnv("LLM_CACHE_FORCE", "false") == "true"}
+	var err error
+	if cfg.TTL, err = time.ParseDuration(getEnv("LLM_CACHE_TTL", "24h")); err != nil {
+		return nil, fmt.Errorf("LLM_CACHE_TTL: %w", err)
+	}
+	maxMB, err := strconv.Atoi(getEnv("LLM_CACHE_MAX_MB", "100"))
+	if err != nil {
+		return nil, fmt.Errorf("LLM_CACHE_MAX_MB: %w", err)
+	}
+	cfg.MaxBytes = int64(maxMB) << 20
+	if cfg.StreamDelay, err = time.ParseDuration(getEnv("LLM_CACHE_STREAM_DELAY", "0s")); err != nil {
+		return nil, fmt.Errorf("LLM_CACHE_STREAM_DELAY: %w", err)
+	}
+	return llm.NewCache(dir, cfg)
+}
+
+// newMock creates the mock provider from the script at path, or with no script if path is empty.
+func newMock(path string) (llm.Provider, error) {
+	var script llm.MockScript
+	if path != "" {
+		var err error
+		if script, err = llm.LoadMockScript(path); err != nil {
+			return nil, err
+		}
+	}
+	return llm.NewMock(script)
+}
+
+// newRateLimiter creates the LLM rate limiter from LLM_REQUESTS_PER_MINUTE,
+// LLM_TOKENS_PER_MINUTE and LLM_MAX_STREAMS, which apply to every model, and
+// LLM_RATE_LIMITS, a JSON object of per-model limits that replace them.
+func newRateLimiter() (*llm.RateLimiter, error) {
+	var defaults llm.RateLimits
+	for _, v := range []struct {
+		env string
+		dst *int
+	}{
+		{"LLM_REQUESTS_PER_MINUTE", &defaults.RequestsPerMinute},
+		{"LLM_TOKENS_PER_MINUTE", &defaults.TokensPerMinute},
+		{"LLM_MAX_STREAMS", &defaults.MaxStreams},
+	} {
+		n, err := strconv.Atoi(getEnv(v.env, "0"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", v.env, err)
+		}
+		*v.dst = n
+	}
+	var perModel map[string]llm.RateLimits
+	if s := os.Getenv("LLM_RATE_LIMITS"); s != "" {
+		if err := json.Unmarshal([]byte(s), &perModel); err != nil {
+			return nil, fmt.Errorf("LLM_RATE_LIMITS: %w", err)
+		}
+	}
+	return llm.NewRateLimiter(defaults, perModel), nil
+}
+
 func getEnv(key, fallback string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"simarena/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// CreateBatch handles POST /api/batches.
+func (h *Handler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Runs < 2 || req.Runs > 20 {
+		http.Error(w, `{"error":"runs must be between 2 and 20"}`, http.StatusBadRequest)
+		return
+	}
+	if _, msg := h.newSimulation(req.Simulation); msg != "" {
+		writeError(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	baseSeed := rand.Int64N(1 << 31)
+	if req.BaseSeed != nil {
+		baseSeed = *req.BaseSeed
+	}
+
+	batch := models.Batch{
+		ID:            uuid.New().String(),
+		Description:   req.Simulation.Description,
+		Runs:          req.Runs,
+		BaseSeed:      baseSeed,
+		SimulationIDs: make([]string, 0, req.Runs),
+		Status:        "running",
+		CreatedAt:     time.Now(),
+	}
+
+	runs := make([]*models.Simulation, 0, req.Runs)
+	for i := 0; i < req.Runs; i++ {
+		sim, _ := h.newSimulation(req.Simulation)
+		sim.BatchID = batch.ID
+		seedRun(&sim, int64(i), baseSeed)
+		runs = append(runs, &sim)
+		batch.SimulationIDs = append(batch.SimulationIDs, sim.ID)
+	}
+
+	if err := h.store.CreateBatch(batch); err != nil {
+		http.Error(w, `{"error":"failed to save batch"}`, http.StatusInternalServerError)
+		return
+	}
+	for i, sim := range runs {
+		if err := h.store.Create(*sim); err != nil {
+			log.Printf("ERROR: failed to save run %d of batch %s: %v", i+1, batch.ID, err)
+			h.abandonRuns(runs[:i])
+			batch.Status = "failed"
+			if err := h.store.UpdateBatch(batch); err != nil {
+				log.Printf("ERROR: failed to update batch: %v", err)
+			}
+			http.Error(w, `{"error":"failed to save simulation"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	resp := batch
+	h.engine.RunBatch(&batch, runs, h.hub.BroadcastBatch)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// abandonRuns marks saved runs of a batch or experiment that could not be started as failed.
+func (h *Handler) abandonRuns(runs []*models.Simulation) {
+	for _, sim := range runs {
+		sim.Status = "failed"
+		if err := h.store.Update(*sim); err != nil {
+			log.Printf("ERROR: failed to update simulation: %v", err)
+		}
+	}
+}
+
+// seedRun gives run i of a batch its own seed. Explicit per-agent seeds are offset
+// the same way so that no agent repeats its output across runs.
+func seedRun(sim *models.Simulation, i, baseSeed int64) {
+	seed := baseSeed + i
+	sim.Sampling.Seed = &seed
+	for j := range sim.Agents {
+		p := sim.Agents[j].Sampling
+		if p == nil || p.Seed == nil {
+			continue
+		}
+		agentSampling := *p
+		agentSeed := *p.Seed + i
+		agentSampling.Seed = &agentSeed
+		sim.Agents[j].Sampling = &agentSampling
+	}
+}
+
+// ListBatches handles GET /api/batches.
+func (h *Handler) ListBatches(w http.ResponseWriter, r *http.Request) {
+	batches, err := h.store.ListBatches()
+	if err != nil {
+		http.Error(w, `{"error":"failed to list batches"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batches)
+}
+
+// GetBatch handles GET /api/batches/{id}.
+func (h *Handler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	batch, err := h.store.GetBatch(id)
+	if err != nil {
+		http.Error(w, `{"error":"batch not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batch)
+}
+
+// BatchWebSocketHandler handles WS /api/batches/{id}/ws.
+func (h *Handler) BatchWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := h.store.GetBatch(id); err != nil {
+		http.Error(w, `{"error":"batch not found"}`, http.StatusNotFound)
+		return
+	}
+
+	h.serveWebSocket(w, r, id)
+}
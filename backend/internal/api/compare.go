@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"simarena/internal/models"
+)
+
+// CompareSimulations handles POST /api/simulations/compare.
+func (h *Handler) CompareSimulations(w http.ResponseWriter, r *http.Request) {
+	var req models.CompareSimulationsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if len(req.SimulationIDs) < 2 || len(req.SimulationIDs) > 10 {
+		http.Error(w, `{"error":"between 2 and 10 simulation_ids are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	sims := make([]*models.Simulation, 0, len(req.SimulationIDs))
+	for _, id := range req.SimulationIDs {
+		sim, err := h.store.Get(id)
+		if err != nil {
+			http.Error(w, `{"error":"simulation not found"}`, http.StatusNotFound)
+			return
+		}
+		sims = append(sims, sim)
+	}
+
+	cmp, err := h.engine.Compare(r.Context(), sims)
+	if err != nil {
+		log.Printf("ERROR: compare simulations: %v", err)
+		http.Error(w, `{"error":"failed to compare simulations"}`, http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cmp)
+}
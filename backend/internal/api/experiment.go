@@ -0,0 +1,210 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"simarena/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// maxExperimentCells caps the size of an experiment's cartesian product.
+const maxExperimentCells = 50
+
+// CreateExperiment handles POST /api/experiments.
+func (h *Handler) CreateExperiment(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateExperimentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if msg := validateAxes(req.Axes); msg != "" {
+		writeError(w, msg, http.StatusBadRequest)
+		return
+	}
+	cells, reqs := expandExperiment(req.Base, req.Axes)
+	if len(cells) > maxExperimentCells {
+		http.Error(w, `{"error":"experiment expands to too many simulations (max 50)"}`, http.StatusBadRequest)
+		return
+	}
+
+	exp := models.Experiment{
+		ID:        uuid.New().String(),
+		Name:      req.Name,
+		Base:      req.Base,
+		Axes:      req.Axes,
+		Cells:     cells,
+		Status:    "running",
+		CreatedAt: time.Now(),
+	}
+
+	runs := make([]*models.Simulation, 0, len(reqs))
+	for i, cellReq := range reqs {
+		sim, msg := h.newSimulation(cellReq)
+		if msg != "" {
+			writeError(w, msg, http.StatusBadRequest)
+			return
+		}
+		sim.ExperimentID = exp.ID
+		exp.Cells[i].SimulationID = sim.ID
+		runs = append(runs, &sim)
+	}
+
+	if err := h.store.CreateExperiment(exp); err != nil {
+		http.Error(w, `{"error":"failed to save experiment"}`, http.StatusInternalServerError)
+		return
+	}
+	for i, sim := range runs {
+		if err := h.store.Create(*sim); err != nil {
+			log.Printf("ERROR: failed to save run %d of experiment %s: %v", i+1, exp.ID, err)
+			h.abandonRuns(runs[:i])
+			exp.Status = "failed"
+			if err := h.store.UpdateExperiment(exp); err != nil {
+				log.Printf("ERROR: failed to update experiment: %v", err)
+			}
+			http.Error(w, `{"error":"failed to save simulation"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	resp := exp
+	h.engine.RunExperiment(&exp, runs)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// validateAxes checks axis values that newSimulation would otherwise silently default.
+func validateAxes(axes models.ExperimentAxes) string {
+	for _, d := range axes.Depth {
+		if d != "shallow" && d != "medium" && d != "deep" {
+			return "depth axis values must be shallow, medium or deep"
+		}
+	}
+	for _, m := range axes.Model {
+		if m == "" {
+			return "model axis values must not be empty"
+		}
+	}
+	for _, v := range axes.Agents {
+		if v.Label == "" || len(v.Agents) == 0 {
+			return "agents axis variants need a label and at least one agent"
+		}
+	}
+	for _, v := range axes.Preconditions {
+		if v.Label == "" {
+			return "preconditions axis variants need a label"
+		}
+	}
+	return ""
+}
+
+// expandExperiment returns the cartesian product of the axes applied to base,
+// as parallel slices of grid cells and the simulation requests for them.
+func expandExperiment(base models.CreateSimulationRequest, axes models.ExperimentAxes) ([]models.ExperimentCell, []models.CreateSimulationRequest) {
+	cells := []models.ExperimentCell{{}}
+	reqs := []models.CreateSimulationRequest{base}
+
+	expand := func(n int, apply func(i int, cell *models.ExperimentCell, req *models.CreateSimulationRequest)) {
+		if n == 0 {
+			return
+		}
+		nextCells := make([]models.ExperimentCell, 0, len(cells)*n)
+		nextReqs := make([]models.CreateSimulationRequest, 0, len(reqs)*n)
+		for j := range cells {
+			for i := 0; i < n; i++ {
+				cell, req := cells[j], reqs[j]
+				apply(i, &cell, &req)
+				nextCells = append(nextCells, cell)
+				nextReqs = append(nextReqs, req)
+			}
+		}
+		cells, reqs = nextCells, nextReqs
+	}
+
+	expand(len(axes.Depth), func(i int, cell *models.ExperimentCell, req *models.CreateSimulationRequest) {
+		cell.Depth = axes.Depth[i]
+		req.Depth = axes.Depth[i]
+	})
+	expand(len(axes.Model), func(i int, cell *models.ExperimentCell, req *models.CreateSimulationRequest) {
+		cell.Model = axes.Model[i]
+		req.Model = axes.Model[i]
+	})
+	expand(len(axes.Agents), func(i int, cell *models.ExperimentCell, req *models.CreateSimulationRequest) {
+		cell.Agents = axes.Agents[i].Label
+		req.Agents = axes.Agents[i].Agents
+	})
+	expand(len(axes.Preconditions), func(i int, cell *models.ExperimentCell, req *models.CreateSimulationRequest) {
+		cell.Preconditions = axes.Preconditions[i].Label
+		req.Preconditions = axes.Preconditions[i].Preconditions
+	})
+	return cells, reqs
+}
+
+// ListExperiments handles GET /api/experiments.
+func (h *Handler) ListExperiments(w http.ResponseWriter, r *http.Request) {
+	exps, err := h.store.ListExperiments()
+	if err != nil {
+		http.Error(w, `{"error":"failed to list experiments"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exps)
+}
+
+// GetExperiment handles GET /api/experiments/{id}.
+func (h *Handler) GetExperiment(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	exp, err := h.store.GetExperiment(id)
+	if err != nil {
+		http.Error(w, `{"error":"experiment not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exp)
+}
+
+// GetExperimentResults handles GET /api/experiments/{id}/results.
+func (h *Handler) GetExperimentResults(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	exp, err := h.store.GetExperiment(id)
+	if err != nil {
+		http.Error(w, `{"error":"experiment not found"}`, http.StatusNotFound)
+		return
+	}
+
+	sims, err := h.store.List()
+	if err != nil {
+		http.Error(w, `{"error":"failed to list simulations"}`, http.StatusInternalServerError)
+		return
+	}
+	byID := make(map[string]*models.Simulation, len(sims))
+	for i := range sims {
+		byID[sims[i].ID] = &sims[i]
+	}
+
+	results := make([]models.ExperimentResult, 0, len(exp.Cells))
+	for _, cell := range exp.Cells {
+		result := models.ExperimentResult{ExperimentCell: cell, Status: "deleted"}
+		if sim, ok := byID[cell.SimulationID]; ok {
+			result.Status = sim.Status
+			result.Steps = len(sim.Steps)
+			result.FinalResult = sim.FinalResult
+			if sim.Evaluation != nil {
+				result.Score = &sim.Evaluation.Score
+			}
+		}
+		results = append(results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
@@ -2,9 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
+	"regexp"
 	"time"
 
+	"simarena/internal/llm"
 	"simarena/internal/models"
 	"simarena/internal/simulation"
 	"simarena/internal/storage"
@@ -15,17 +18,22 @@ import (
 
 // Handler holds dependencies for HTTP handlers.
 type Handler struct {
-	store  *storage.JSONStore
-	engine *simulation.Engine
-	hub    *Hub
+	store   *storage.JSONStore
+	engine  *simulation.Engine
+	hub     *Hub
+	limiter *llm.RateLimiter
+	pool    *llm.Pool
 }
 
-// NewHandler creates a new Handler.
-func NewHandler(store *storage.JSONStore, engine *simulation.Engine, hub *Hub) *Handler {
+// NewHandler creates a new Handler. limiter and pool are the LLM rate limiter and
+// endpoint pool to report on, or nil.
+func NewHandler(store *storage.JSONStore, engine *simulation.Engine, hub *Hub, limiter *llm.RateLimiter, pool *llm.Pool) *Handler {
 	return &Handler{
-		store:  store,
-		engine: engine,
-		hub:    hub,
+		store:   store,
+		engine:  engine,
+		hub:     hub,
+		limiter: limiter,
+		pool:    pool,
 	}
 }
 
@@ -37,15 +45,79 @@ func (h *Handler) CreateSimulation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Description == "" {
-		http.Error(w, `{"error":"description is required"}`, http.StatusBadRequest)
+	sim, msg := h.newSimulation(req)
+	if msg != "" {
+		writeError(w, msg, http.StatusBadRequest)
 		return
 	}
-	if req.Rounds < 1 || req.Rounds > 50 {
-		http.Error(w, `{"error":"rounds must be between 1 and 50"}`, http.StatusBadRequest)
+
+	if err := h.store.Create(sim); err != nil {
+		http.Error(w, `{"error":"failed to save simulation"}`, http.StatusInternalServerError)
 		return
 	}
 
+	h.engine.Run(&sim)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sim)
+}
+
+// newSimulation validates req and builds a simulation ready to run.
+// It returns an error message, or "" if req is valid.
+func (h *Handler) newSimulation(req models.CreateSimulationRequest) (models.Simulation, string) {
+	if req.Description == "" {
+		return models.Simulation{}, "description is required"
+	}
+	if req.Rounds < 1 || req.Rounds > 50 {
+		return models.Simulation{}, "rounds must be between 1 and 50"
+	}
+
+	if msg := validateSampling(&req.Sampling); msg != "" {
+		return models.Simulation{}, msg
+	}
+	for _, a := range req.Agents {
+		if msg := validateSampling(a.Sampling); msg != "" {
+			return models.Simulation{}, "agent " + msg
+		}
+		if a.JoinRound < 0 || a.JoinRound > req.Rounds {
+			return models.Simulation{}, "agent join_round must be within the simulation's rounds"
+		}
+		if a.LeaveRound != 0 && (a.LeaveRound <= max(a.JoinRound, 1) || a.LeaveRound > req.Rounds) {
+			return models.Simulation{}, "agent leave_round must be after join_round and within the simulation's rounds"
+		}
+	}
+	for i := range req.Votes {
+		if msg := normalizeVote(&req.Votes[i]); msg != "" {
+			return models.Simulation{}, msg
+		}
+		if req.Votes[i].Round < 1 || req.Votes[i].Round > req.Rounds {
+			return models.Simulation{}, "vote round must be within the simulation's rounds"
+		}
+	}
+	if len(req.Tools) > 0 && !h.engine.Capabilities().Tools {
+		return models.Simulation{}, "the LLM provider does not support tools"
+	}
+	for _, name := range req.Tools {
+		if _, ok := h.engine.Tools().Get(name); !ok {
+			return models.Simulation{}, "unknown tool " + name
+		}
+	}
+	if msg := validateGuardrails(req.Guardrails); msg != "" {
+		return models.Simulation{}, msg
+	}
+	if msg := validateFailurePolicy(req.FailurePolicy); msg != "" {
+		return models.Simulation{}, msg
+	}
+	if msg := h.validateBudget(req.Budget); msg != "" {
+		return models.Simulation{}, msg
+	}
+	if req.RubricID != "" {
+		if _, err := h.store.GetRubric(req.RubricID); err != nil {
+			return models.Simulation{}, "rubric not found"
+		}
+	}
+
 	// Default agents: single "Agent" with no role (backward compat)
 	agents := make([]models.Agent, 0, len(req.Agents))
 	if len(req.Agents) == 0 {
@@ -59,9 +131,13 @@ func (h *Handler) CreateSimulation(w http.ResponseWriter, r *http.Request) {
 				a.Name = "Agent"
 			}
 			agents = append(agents, models.Agent{
-				ID:   uuid.New().String(),
-				Name: a.Name,
-				Role: a.Role,
+				ID:         uuid.New().String(),
+				Name:       a.Name,
+				Role:       a.Role,
+				Sampling:   a.Sampling,
+				VoteWeight: a.VoteWeight,
+				JoinRound:  a.JoinRound,
+				LeaveRound: a.LeaveRound,
 			})
 		}
 	}
@@ -87,21 +163,131 @@ func (h *Handler) CreateSimulation(w http.ResponseWriter, r *http.Request) {
 		Agents:         agents,
 		Language:       lang,
 		Depth:          depth,
+		Model:          req.Model,
+		Structured:     req.Structured,
+		Tools:          req.Tools,
+		Votes:          req.Votes,
+		Sampling:       req.Sampling,
+		RubricID:       req.RubricID,
+		Guardrails:     req.Guardrails,
+		FailurePolicy:  req.FailurePolicy,
+		ModelOptions:   req.ModelOptions,
+		Record:         req.Record,
+		Budget:         req.Budget,
 		Status:         "running",
 		Steps:          []models.Step{},
 		CreatedAt:      time.Now(),
 	}
+	return sim, ""
+}
 
-	if err := h.store.Create(sim); err != nil {
-		http.Error(w, `{"error":"failed to save simulation"}`, http.StatusInternalServerError)
-		return
+// writeError responds with a JSON error body, for messages that are not string literals.
+func writeError(w http.ResponseWriter, msg string, status int) {
+	body, _ := json.Marshal(map[string]string{"error": msg})
+	http.Error(w, string(body), status)
+}
+
+// validateGuardrails checks a guardrail configuration, which may be nil.
+// It returns an error message, or "" if it is valid.
+func validateGuardrails(g *models.GuardrailConfig) string {
+	if g == nil {
+		return ""
+	}
+	for _, p := range g.Blocklist {
+		if _, err := regexp.Compile(p); err != nil {
+			return "invalid blocklist pattern " + p
+		}
 	}
+	if g.BlockAction != "" && g.BlockAction != models.GuardRedact && g.BlockAction != models.GuardRegenerate {
+		return "guardrails block_action must be redact or regenerate"
+	}
+	if g.MaxChars < 0 {
+		return "guardrails max_chars must not be negative"
+	}
+	return ""
+}
 
-	h.engine.Run(&sim)
+// validateFailurePolicy checks a failure policy, which may be nil.
+// It returns an error message, or "" if it is valid.
+func validateFailurePolicy(p *models.FailurePolicy) string {
+	if p == nil {
+		return ""
+	}
+	if p.Retries < 0 || p.Retries > 5 {
+		return "failure_policy retries must be between 0 and 5"
+	}
+	if p.BackoffSeconds < 0 || p.BackoffSeconds > 60 {
+		return "failure_policy backoff_seconds must be between 0 and 60"
+	}
+	switch p.OnFailure {
+	case "", models.FailureFail, models.FailureSkip:
+	case models.FailureFallback:
+		if p.FallbackModel == "" {
+			return "failure_policy fallback_model is required for on_failure fallback"
+		}
+	default:
+		return "failure_policy on_failure must be fail, skip or fallback"
+	}
+	return ""
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(sim)
+// validateBudget checks a budget, which may be nil, and fills in its defaults.
+// It returns an error message, or "" if it is valid.
+func (h *Handler) validateBudget(b *models.Budget) string {
+	if b == nil {
+		return ""
+	}
+	if b.MaxTokens < 0 || b.MaxCost < 0 {
+		return "budget must not be negative"
+	}
+	if (b.MaxTokens > 0) == (b.MaxCost > 0) {
+		return "budget needs exactly one of max_tokens and max_cost"
+	}
+	if b.MaxCost > 0 {
+		prices, err := h.store.ListPrices()
+		if err != nil || len(prices) == 0 {
+			return "budget max_cost needs model prices; set them with PUT /api/prices"
+		}
+	}
+	for _, t := range b.WarnAt {
+		if t <= 0 || t >= 1 {
+			return "budget warn_at thresholds must be between 0 and 1"
+		}
+	}
+	if b.SummaryReserve < 0 || b.SummaryReserve >= 1 {
+		return "budget summary_reserve must be at least 0 and less than 1"
+	}
+	if b.WarnAt == nil {
+		b.WarnAt = models.DefaultBudgetWarnAt
+	}
+	if b.SummaryReserve == 0 {
+		b.SummaryReserve = models.DefaultBudgetSummaryReserve
+	}
+	return ""
+}
+
+// validateSampling checks sampling parameters against the ranges accepted by
+// OpenAI-compatible servers. It returns an error message, or "" if p is valid.
+func validateSampling(p *models.SamplingParams) string {
+	if p == nil {
+		return ""
+	}
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+		return "temperature must be between 0 and 2"
+	}
+	if p.TopP != nil && (*p.TopP <= 0 || *p.TopP > 1) {
+		return "top_p must be greater than 0 and at most 1"
+	}
+	if p.PresencePenalty != nil && (*p.PresencePenalty < -2 || *p.PresencePenalty > 2) {
+		return "presence_penalty must be between -2 and 2"
+	}
+	if p.FrequencyPenalty != nil && (*p.FrequencyPenalty < -2 || *p.FrequencyPenalty > 2) {
+		return "frequency_penalty must be between -2 and 2"
+	}
+	if len(p.Stop) > 4 {
+		return "at most 4 stop sequences are allowed"
+	}
+	return ""
 }
 
 // ListSimulations handles GET /api/simulations.
@@ -112,6 +298,21 @@ func (h *Handler) ListSimulations(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	filter, msg := parseScoreFilter(r)
+	if msg != "" {
+		writeError(w, msg, http.StatusBadRequest)
+		return
+	}
+	if filter.active() {
+		filtered := make([]models.Simulation, 0, len(sims))
+		for _, sim := range sims {
+			if filter.match(&sim) {
+				filtered = append(filtered, sim)
+			}
+		}
+		sims = filtered
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(sims)
 }
@@ -140,6 +341,57 @@ func (h *Handler) DeleteSimulation(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ListTools handles GET /api/tools.
+func (h *Handler) ListTools(w http.ResponseWriter, r *http.Request) {
+	type toolInfo struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Parameters  map[string]any `json:"parameters"`
+	}
+	tools := h.engine.Tools().List()
+	infos := make([]toolInfo, 0, len(tools))
+	for _, t := range tools {
+		infos = append(infos, toolInfo{Name: t.Name, Description: t.Description, Parameters: t.Parameters})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(infos)
+}
+
+// ListModels handles GET /api/models.
+// It reports the LLM provider's capabilities and, if the provider can list them, its models.
+func (h *Handler) ListModels(w http.ResponseWriter, r *http.Request) {
+	names, ok, err := h.engine.Models(r.Context())
+	if err != nil {
+		log.Printf("ERROR: list models: %v", err)
+		http.Error(w, `{"error":"failed to list models"}`, http.StatusBadGateway)
+		return
+	}
+	resp := struct {
+		Models       []string         `json:"models"`
+		Listed       bool             `json:"listed"` // false if the provider cannot list models
+		Capabilities llm.Capabilities `json:"capabilities"`
+	}{Models: names, Listed: ok, Capabilities: h.engine.Capabilities()}
+	if resp.Models == nil {
+		resp.Models = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ListRateLimits handles GET /api/ratelimits, reporting the load of every LLM endpoint and model.
+func (h *Handler) ListRateLimits(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.limiter.Status())
+}
+
+// ListEndpoints handles GET /api/endpoints, reporting the health of every pooled LLM endpoint.
+func (h *Handler) ListEndpoints(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.pool.Status())
+}
+
 // WebSocketHandler handles WS /api/simulations/{id}/ws.
 func (h *Handler) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -151,6 +403,11 @@ func (h *Handler) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.serveWebSocket(w, r, id)
+}
+
+// serveWebSocket upgrades the connection and registers it with the hub under id.
+func (h *Handler) serveWebSocket(w http.ResponseWriter, r *http.Request, id string) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"simarena/internal/models"
+)
+
+// ListPrices handles GET /api/prices.
+func (h *Handler) ListPrices(w http.ResponseWriter, r *http.Request) {
+	prices, err := h.store.ListPrices()
+	if err != nil {
+		http.Error(w, `{"error":"failed to list prices"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prices)
+}
+
+// SavePrices handles PUT /api/prices. The body replaces the whole price table.
+func (h *Handler) SavePrices(w http.ResponseWriter, r *http.Request) {
+	var prices []models.ModelPrice
+	if err := json.NewDecoder(r.Body).Decode(&prices); err != nil {
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	seen := make(map[string]bool, len(prices))
+	for _, p := range prices {
+		if p.Model == "" {
+			http.Error(w, `{"error":"every price needs a model"}`, http.StatusBadRequest)
+			return
+		}
+		if p.Input < 0 || p.Output < 0 {
+			writeError(w, "prices of "+p.Model+" must not be negative", http.StatusBadRequest)
+			return
+		}
+		if seen[p.Model] {
+			writeError(w, "duplicate price for "+p.Model, http.StatusBadRequest)
+			return
+		}
+		seen[p.Model] = true
+	}
+	if prices == nil {
+		prices = []models.ModelPrice{}
+	}
+	if err := h.store.SavePrices(prices); err != nil {
+		http.Error(w, `{"error":"failed to save prices"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prices)
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"simarena/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ReplaySimulation handles POST /api/simulations/{id}/replay.
+// It re-runs a recorded simulation as a new one, serving LLM responses from its cassette.
+func (h *Handler) ReplaySimulation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	source, err := h.store.Get(id)
+	if err != nil {
+		http.Error(w, `{"error":"simulation not found"}`, http.StatusNotFound)
+		return
+	}
+	if source.Status == "running" {
+		http.Error(w, `{"error":"simulation is still running"}`, http.StatusConflict)
+		return
+	}
+	interactions, err := h.store.GetCassette(source.ID)
+	if err != nil {
+		http.Error(w, `{"error":"simulation has no recorded cassette"}`, http.StatusNotFound)
+		return
+	}
+
+	sim := *source
+	sim.ID = uuid.New().String()
+	sim.Record = false
+	sim.ReplayOf = source.ID
+	sim.Divergences = nil
+	sim.Status = "running"
+	sim.Steps = []models.Step{}
+	sim.FinalResult = ""
+	sim.Evaluation = nil
+	sim.Usage = models.TokenUsage{}
+	sim.BudgetWarnings = nil
+	sim.BatchID = ""
+	sim.ExperimentID = ""
+	sim.CreatedAt = time.Now()
+
+	if err := h.store.Create(sim); err != nil {
+		http.Error(w, `{"error":"failed to save simulation"}`, http.StatusInternalServerError)
+		return
+	}
+
+	h.engine.Replay(&sim, interactions)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sim)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"simarena/internal/models"
+	"simarena/internal/simulation"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// AddAgent handles POST /api/simulations/{id}/agents.
+// The agent joins a running simulation from the next round.
+func (h *Handler) AddAgent(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req models.AgentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if msg := validateSampling(req.Sampling); msg != "" {
+		writeError(w, "agent "+msg, http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		req.Name = "Agent"
+	}
+
+	if _, err := h.store.Get(id); err != nil {
+		http.Error(w, `{"error":"simulation not found"}`, http.StatusNotFound)
+		return
+	}
+
+	agent := models.Agent{
+		ID:         uuid.New().String(),
+		Name:       req.Name,
+		Role:       req.Role,
+		Sampling:   req.Sampling,
+		VoteWeight: req.VoteWeight,
+	}
+	switch err := h.engine.AddAgent(id, agent); err {
+	case simulation.ErrNotRunning:
+		http.Error(w, `{"error":"simulation is not running"}`, http.StatusConflict)
+		return
+	case simulation.ErrTooLate:
+		http.Error(w, `{"error":"the simulation is in its last round"}`, http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(agent)
+}
+
+// RemoveAgent handles DELETE /api/simulations/{id}/agents/{agentID}.
+// The agent leaves a running simulation from the next round; its history is kept.
+func (h *Handler) RemoveAgent(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	agentID := chi.URLParam(r, "agentID")
+	var req models.RemoveAgentRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	sim, err := h.store.Get(id)
+	if err != nil {
+		http.Error(w, `{"error":"simulation not found"}`, http.StatusNotFound)
+		return
+	}
+	i := slices.IndexFunc(sim.Agents, func(a models.Agent) bool { return a.ID == agentID })
+	if i < 0 {
+		http.Error(w, `{"error":"agent not found"}`, http.StatusNotFound)
+		return
+	}
+
+	switch err := h.engine.RemoveAgent(id, sim.Agents[i], req.Reason); err {
+	case simulation.ErrNotRunning:
+		http.Error(w, `{"error":"simulation is not running"}`, http.StatusConflict)
+		return
+	case simulation.ErrTooLate:
+		http.Error(w, `{"error":"the simulation is in its last round"}`, http.StatusConflict)
+		return
+	case simulation.ErrNotPresent:
+		http.Error(w, `{"error":"agent has left, is leaving or has not joined yet"}`, http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
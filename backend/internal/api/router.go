@@ -14,7 +14,7 @@ func NewRouter(h *Handler, corsOrigin string) *chi.Mux {
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{corsOrigin},
-		AllowedMethods:   []string{"GET", "POST", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Content-Type"},
 		AllowCredentials: true,
 	}))
@@ -22,9 +22,45 @@ func NewRouter(h *Handler, corsOrigin string) *chi.Mux {
 	r.Route("/api/simulations", func(r chi.Router) {
 		r.Post("/", h.CreateSimulation)
 		r.Get("/", h.ListSimulations)
+		r.Post("/compare", h.CompareSimulations)
 		r.Get("/{id}", h.GetSimulation)
 		r.Delete("/{id}", h.DeleteSimulation)
 		r.Get("/{id}/ws", h.WebSocketHandler)
+		r.Post("/{id}/evaluate", h.EvaluateSimulation)
+		r.Post("/{id}/replay", h.ReplaySimulation)
+		r.Patch("/{id}/steps/{seq}", h.EditStep)
+		r.Post("/{id}/steps/{seq}/regenerate", h.RegenerateStep)
+		r.Post("/{id}/votes", h.RequestVote)
+		r.Post("/{id}/agents", h.AddAgent)
+		r.Delete("/{id}/agents/{agentID}", h.RemoveAgent)
+	})
+
+	r.Get("/api/tools", h.ListTools)
+	r.Get("/api/models", h.ListModels)
+	r.Get("/api/prices", h.ListPrices)
+	r.Put("/api/prices", h.SavePrices)
+	r.Get("/api/ratelimits", h.ListRateLimits)
+	r.Get("/api/endpoints", h.ListEndpoints)
+
+	r.Route("/api/rubrics", func(r chi.Router) {
+		r.Post("/", h.CreateRubric)
+		r.Get("/", h.ListRubrics)
+		r.Get("/{id}", h.GetRubric)
+		r.Delete("/{id}", h.DeleteRubric)
+	})
+
+	r.Route("/api/batches", func(r chi.Router) {
+		r.Post("/", h.CreateBatch)
+		r.Get("/", h.ListBatches)
+		r.Get("/{id}", h.GetBatch)
+		r.Get("/{id}/ws", h.BatchWebSocketHandler)
+	})
+
+	r.Route("/api/experiments", func(r chi.Router) {
+		r.Post("/", h.CreateExperiment)
+		r.Get("/", h.ListExperiments)
+		r.Get("/{id}", h.GetExperiment)
+		r.Get("/{id}/results", h.GetExperimentResults)
 	})
 
 	return r
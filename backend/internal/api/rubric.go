@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"simarena/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// CreateRubric handles POST /api/rubrics.
+func (h *Handler) CreateRubric(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateRubricRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, `{"error":"name is required"}`, http.StatusBadRequest)
+		return
+	}
+	if len(req.Criteria) == 0 {
+		http.Error(w, `{"error":"at least one criterion is required"}`, http.StatusBadRequest)
+		return
+	}
+	seen := make(map[string]bool, len(req.Criteria))
+	for i, c := range req.Criteria {
+		if c.Name == "" || seen[c.Name] {
+			http.Error(w, `{"error":"criterion names must be non-empty and unique"}`, http.StatusBadRequest)
+			return
+		}
+		seen[c.Name] = true
+		// Default scale: 1–5
+		if c.ScaleMin == 0 && c.ScaleMax == 0 {
+			req.Criteria[i].ScaleMin, req.Criteria[i].ScaleMax = 1, 5
+		} else if c.ScaleMax <= c.ScaleMin {
+			http.Error(w, `{"error":"scale_max must be greater than scale_min"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	rubric := models.Rubric{
+		ID:         uuid.New().String(),
+		Name:       req.Name,
+		Criteria:   req.Criteria,
+		JudgeModel: req.JudgeModel,
+		CreatedAt:  time.Now(),
+	}
+	if err := h.store.CreateRubric(rubric); err != nil {
+		http.Error(w, `{"error":"failed to save rubric"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rubric)
+}
+
+// ListRubrics handles GET /api/rubrics.
+func (h *Handler) ListRubrics(w http.ResponseWriter, r *http.Request) {
+	rubrics, err := h.store.ListRubrics()
+	if err != nil {
+		http.Error(w, `{"error":"failed to list rubrics"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rubrics)
+}
+
+// GetRubric handles GET /api/rubrics/{id}.
+func (h *Handler) GetRubric(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	rubric, err := h.store.GetRubric(id)
+	if err != nil {
+		http.Error(w, `{"error":"rubric not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rubric)
+}
+
+// DeleteRubric handles DELETE /api/rubrics/{id}.
+func (h *Handler) DeleteRubric(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.store.DeleteRubric(id); err != nil {
+		http.Error(w, `{"error":"rubric not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// EvaluateSimulation handles POST /api/simulations/{id}/evaluate.
+// It scores a finished simulation against the rubric in the background; the evaluation
+// appears on the simulation once the judge is done.
+func (h *Handler) EvaluateSimulation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req models.EvaluateSimulationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	sim, err := h.store.Get(id)
+	if err != nil {
+		http.Error(w, `{"error":"simulation not found"}`, http.StatusNotFound)
+		return
+	}
+	if sim.Status == "running" {
+		http.Error(w, `{"error":"simulation is still running"}`, http.StatusConflict)
+		return
+	}
+	rubric, err := h.store.GetRubric(req.RubricID)
+	if err != nil {
+		http.Error(w, `{"error":"rubric not found"}`, http.StatusNotFound)
+		return
+	}
+
+	h.engine.EvaluateLater(sim, rubric)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(sim)
+}
+
+// scoreFilter holds the evaluation filters accepted by ListSimulations.
+type scoreFilter struct {
+	rubricID string
+	minScore *float64
+	maxScore *float64
+}
+
+// parseScoreFilter reads the rubric_id, min_score and max_score query parameters.
+// It returns an error message, or "" if the parameters are valid.
+func parseScoreFilter(r *http.Request) (scoreFilter, string) {
+	q := r.URL.Query()
+	f := scoreFilter{rubricID: q.Get("rubric_id")}
+	for _, p := range []struct {
+		name string
+		dst  **float64
+	}{{"min_score", &f.minScore}, {"max_score", &f.maxScore}} {
+		raw := q.Get(p.name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return f, p.name + " must be a number"
+		}
+		*p.dst = &v
+	}
+	return f, ""
+}
+
+func (f scoreFilter) active() bool {
+	return f.rubricID != "" || f.minScore != nil || f.maxScore != nil
+}
+
+// match reports whether sim has an evaluation satisfying the filter.
+func (f scoreFilter) match(sim *models.Simulation) bool {
+	eval := sim.Evaluation
+	if eval == nil {
+		return false
+	}
+	if f.rubricID != "" && eval.RubricID != f.rubricID {
+		return false
+	}
+	if f.minScore != nil && eval.Score < *f.minScore {
+		return false
+	}
+	if f.maxScore != nil && eval.Score > *f.maxScore {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"simarena/internal/models"
+	"simarena/internal/simulation"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// stepIndex reads the {seq} URL parameter: the index of a step in the simulation's transcript.
+// It returns -1 if seq is not a valid index.
+func stepIndex(r *http.Request, sim *models.Simulation) int {
+	seq, err := strconv.Atoi(chi.URLParam(r, "seq"))
+	if err != nil || seq < 0 || seq >= len(sim.Steps) {
+		return -1
+	}
+	return seq
+}
+
+// RegenerateStep handles POST /api/simulations/{id}/steps/{seq}/regenerate.
+func (h *Handler) RegenerateStep(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req models.RegenerateStepRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Mode == "" {
+		req.Mode = models.RegenerateStale
+	}
+	if req.Mode != models.RegenerateStale && req.Mode != models.RegenerateRerun {
+		http.Error(w, `{"error":"mode must be stale or rerun"}`, http.StatusBadRequest)
+		return
+	}
+
+	sim, err := h.store.Get(id)
+	if err != nil {
+		http.Error(w, `{"error":"simulation not found"}`, http.StatusNotFound)
+		return
+	}
+	if sim.Status == "running" {
+		http.Error(w, `{"error":"simulation is still running"}`, http.StatusConflict)
+		return
+	}
+	seq := stepIndex(r, sim)
+	if seq < 0 {
+		http.Error(w, `{"error":"step not found"}`, http.StatusNotFound)
+		return
+	}
+	if kind := sim.Steps[seq].Kind; kind != "" && kind != models.StepKindSkipped {
+		http.Error(w, `{"error":"only agent turns can be regenerated"}`, http.StatusBadRequest)
+		return
+	}
+
+	switch _, err := h.engine.Regenerate(sim, seq, req.Mode); err {
+	case simulation.ErrBusy:
+		http.Error(w, `{"error":"simulation is busy"}`, http.StatusConflict)
+		return
+	case simulation.ErrBudgetExhausted:
+		http.Error(w, `{"error":"simulation budget is exhausted"}`, http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(sim)
+}
+
+// EditStep handles PATCH /api/simulations/{id}/steps/{seq}.
+// With continue set, the steps after the edited one are dropped and the simulation runs on from it.
+func (h *Handler) EditStep(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req models.EditStepRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Content == "" {
+		http.Error(w, `{"error":"content is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	sim, err := h.store.Get(id)
+	if err != nil {
+		http.Error(w, `{"error":"simulation not found"}`, http.StatusNotFound)
+		return
+	}
+	if sim.Status == "running" {
+		http.Error(w, `{"error":"simulation is still running"}`, http.StatusConflict)
+		return
+	}
+	seq := stepIndex(r, sim)
+	if seq < 0 {
+		http.Error(w, `{"error":"step not found"}`, http.StatusNotFound)
+		return
+	}
+	if sim.Steps[seq].Kind != "" {
+		http.Error(w, `{"error":"only agent turns can be edited"}`, http.StatusBadRequest)
+		return
+	}
+
+	switch err := h.engine.EditStep(sim, seq, req.Content, req.Editor); err {
+	case nil:
+	case simulation.ErrBusy:
+		http.Error(w, `{"error":"simulation is busy"}`, http.StatusConflict)
+		return
+	default:
+		http.Error(w, `{"error":"failed to save simulation"}`, http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if req.Continue {
+		if _, err := h.engine.Continue(sim, seq); err != nil {
+			http.Error(w, `{"error":"step saved, but the simulation is busy"}`, http.StatusConflict)
+			return
+		}
+		status = http.StatusAccepted
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(sim)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"simarena/internal/models"
+	"simarena/internal/simulation"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RequestVote handles POST /api/simulations/{id}/votes.
+// The vote is held after the simulation's current round; its round field is ignored.
+func (h *Handler) RequestVote(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var vote models.VoteConfig
+	if err := json.NewDecoder(r.Body).Decode(&vote); err != nil {
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if msg := normalizeVote(&vote); msg != "" {
+		writeError(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.store.Get(id); err != nil {
+		http.Error(w, `{"error":"simulation not found"}`, http.StatusNotFound)
+		return
+	}
+	switch err := h.engine.RequestVote(id, vote); err {
+	case simulation.ErrNotRunning:
+		http.Error(w, `{"error":"simulation is not running"}`, http.StatusConflict)
+		return
+	case simulation.ErrTooLate:
+		http.Error(w, `{"error":"the last round's votes have already been held"}`, http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// normalizeVote fills in the default options and rule and validates the vote.
+// It returns an error message, or "" if the vote is valid.
+func normalizeVote(v *models.VoteConfig) string {
+	v.Proposal = strings.TrimSpace(v.Proposal)
+	if v.Proposal == "" {
+		return "vote proposal is required"
+	}
+	if v.Eliminate {
+		v.Options = nil // the present agents' names, filled in when the vote is held
+	} else if len(v.Options) == 0 {
+		v.Options = []string{"yes", "no"}
+	}
+	if len(v.Options) < 2 && !v.Eliminate {
+		return "a vote needs at least two options"
+	}
+	seen := make(map[string]bool, len(v.Options))
+	for _, opt := range v.Options {
+		key := strings.ToLower(strings.TrimSpace(opt))
+		if key == "" || key == models.VoteAbstain || seen[key] {
+			return "vote options must be non-empty, unique and not \"abstain\""
+		}
+		seen[key] = true
+	}
+	switch v.Rule {
+	case "":
+		v.Rule = models.VoteRuleMajority
+	case models.VoteRuleMajority, models.VoteRuleUnanimity, models.VoteRuleWeighted:
+	default:
+		return "vote rule must be majority, unanimity or weighted"
+	}
+	return ""
+}
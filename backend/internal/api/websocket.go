@@ -17,7 +17,8 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// Hub manages WebSocket connections grouped by simulation ID.
+// Hub manages WebSocket connections grouped by simulation ID, or by the ID of a
+// group of simulations such as a batch.
 type Hub struct {
 	mu    sync.RWMutex
 	conns map[string]map[*websocket.Conn]bool
@@ -54,6 +55,16 @@ func (h *Hub) Unregister(simID string, conn *websocket.Conn) {
 
 // BroadcastStep sends a step to all connected clients for a simulation.
 func (h *Hub) BroadcastStep(simID string, step models.Step) {
+	h.Broadcast(simID, step)
+}
+
+// BroadcastBatch sends a batch progress update to all connected clients for a batch.
+func (h *Hub) BroadcastBatch(batch models.Batch) {
+	h.Broadcast(batch.ID, batch)
+}
+
+// Broadcast sends v as JSON to all clients connected under the given simulation or group ID.
+func (h *Hub) Broadcast(simID string, v any) {
 	h.mu.RLock()
 	clients := h.conns[simID]
 	h.mu.RUnlock()
@@ -62,9 +73,9 @@ func (h *Hub) BroadcastStep(simID string, step models.Step) {
 		return
 	}
 
-	data, err := json.Marshal(step)
+	data, err := json.Marshal(v)
 	if err != nil {
-		log.Printf("ERROR: marshal step for broadcast: %v", err)
+		log.Printf("ERROR: marshal message for broadcast: %v", err)
 		return
 	}
 
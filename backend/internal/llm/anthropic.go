@@ -0,0 +1,354 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// anthropicVersion is the Messages API version the provider speaks.
+const anthropicVersion = "2023-06-01"
+
+// DefaultAnthropicBaseURL is the Anthropic API endpoint, without the /messages path.
+const DefaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+
+// Anthropic is a Provider for the Anthropic Messages API. Config.BaseURL is the API root
+// including /v1, so a local stand-in can be used; Config.ResponseFormat is ignored.
+type Anthropic struct {
+	cfg  Config
+	http *http.Client
+}
+
+var _ Provider = (*Anthropic)(nil)
+
+// NewAnthropic creates a new Anthropic Messages API provider.
+func NewAnthropic(cfg Config) *Anthropic {
+	return &Anthropic{
+		cfg: cfg,
+		http: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+	}
+}
+
+// anthropicRequest is the request body of POST /messages.
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Stream        bool               `json:"stream,omitempty"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Tools         []anthropicTool    `json:"tools,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"` // "user" or "assistant"
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock is a content block; which fields are set depends on Type.
+type anthropicBlock struct {
+	Type      string          `json:"type"` // "text", "tool_use" or "tool_result"
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicResponse is the non-streaming response body.
+type anthropicResponse struct {
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
+}
+
+// anthropicEvent is a server-sent event of a streaming response; fields depend on Type.
+type anthropicEvent struct {
+	Type         string             `json:"type"`
+	Index        int                `json:"index"`
+	Message      *anthropicResponse `json:"message"`       // message_start
+	ContentBlock *anthropicBlock    `json:"content_block"` // content_block_start
+	Delta        struct {
+		Type        string `json:"type"` // "text_delta" or "input_json_delta"
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"` // message_delta
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage"` // message_delta
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Capabilities implements Provider.
+func (a *Anthropic) Capabilities() Capabilities {
+	return Capabilities{Streaming: true, Tools: true}
+}
+
+// ChatCompletion implements Provider.
+func (a *Anthropic) ChatCompletion(ctx context.Context, messages []ChatMessage, opts Options) (*Completion, error) {
+	result, attempts, err := a.cfg.send(ctx, messages, opts, false, nil, func(func(string)) (*Completion, error) {
+		return a.doMessages(ctx, messages, opts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("anthropic messages request failed after %d attempts: %w", attempts, err)
+	}
+	return result, nil
+}
+
+// ChatCompletionStream implements Provider.
+func (a *Anthropic) ChatCompletionStream(ctx context.Context, messages []ChatMessage, opts Options, onChunk func(delta string)) (*Completion, error) {
+	result, attempts, err := a.cfg.send(ctx, messages, opts, true, onChunk, func(onChunk func(string)) (*Completion, error) {
+		return a.doMessagesStream(ctx, messages, opts, onChunk)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("anthropic streaming messages request failed after %d attempts: %w", attempts, err)
+	}
+	return result, nil
+}
+
+// requestBody converts an OpenAI-style conversation: system messages are joined into the
+// system field, and tool results become tool_result blocks of a user message.
+func (a *Anthropic) requestBody(messages []ChatMessage, opts Options, stream bool) anthropicRequest {
+	req := anthropicRequest{
+		Model:         a.cfg.Model,
+		MaxTokens:     a.cfg.MaxTokens,
+		Stream:        stream,
+		Temperature:   opts.Sampling.Temperature,
+		TopP:          opts.Sampling.TopP,
+		StopSequences: opts.Sampling.Stop,
+	}
+	if opts.Model != "" {
+		req.Model = opts.Model
+	}
+	if opts.MaxTokens > 0 {
+		req.MaxTokens = opts.MaxTokens
+	}
+	for _, t := range opts.Tools {
+		req.Tools = append(req.Tools, anthropicTool{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			InputSchema: t.Function.Parameters,
+		})
+	}
+
+	var system []string
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			system = append(system, m.Content)
+		case "tool":
+			block := anthropicBlock{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content}
+			// Results of one assistant turn's calls must share a single user message.
+			if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == "user" && req.Messages[n-1].Content[0].Type == "tool_result" {
+				req.Messages[n-1].Content = append(req.Messages[n-1].Content, block)
+			} else {
+				req.Messages = append(req.Messages, anthropicMessage{Role: "user", Content: []anthropicBlock{block}})
+			}
+		case "assistant":
+			var blocks []anthropicBlock
+			if m.Content != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: m.Content})
+			}
+			for _, call := range m.ToolCalls {
+				input := json.RawMessage(call.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: input})
+			}
+			if len(blocks) > 0 {
+				req.Messages = append(req.Messages, anthropicMessage{Role: "assistant", Content: blocks})
+			}
+		default:
+			req.Messages = append(req.Messages, anthropicMessage{Role: "user", Content: []anthropicBlock{{Type: "text", Text: m.Content}}})
+		}
+	}
+	req.System = strings.Join(system, "\n\n")
+	return req
+}
+
+// post sends a Messages API request and returns the response if its status is 200.
+func (a *Anthropic) post(ctx context.Context, body anthropicRequest) (*http.Response, error) {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.BaseURL+"/messages", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", a.cfg.APIKey)
+	req.Header.Set("Anthropic-Version", anthropicVersion)
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newAPIError("anthropic", resp)
+	}
+	return resp, nil
+}
+
+func (a *Anthropic) doMessages(ctx context.Context, messages []ChatMessage, opts Options) (*Completion, error) {
+	resp, err := a.post(ctx, a.requestBody(messages, opts, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var msg anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	result := &Completion{
+		FinishReason: anthropicFinishReason(msg.StopReason),
+		Usage:        &Usage{PromptTokens: msg.Usage.InputTokens, CompletionTokens: msg.Usage.OutputTokens},
+		Model:        msg.Model,
+	}
+	var text strings.Builder
+	for _, block := range msg.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			result.ToolCalls = append(result.ToolCalls, ToolCall{
+				ID:       block.ID,
+				Type:     "function",
+				Function: FunctionCall{Name: block.Name, Arguments: string(block.Input)},
+			})
+		}
+	}
+	result.Content = text.String()
+	return result, nil
+}
+
+func (a *Anthropic) doMessagesStream(ctx context.Context, messages []ChatMessage, opts Options, onChunk func(delta string)) (*Completion, error) {
+	resp, err := a.post(ctx, a.requestBody(messages, opts, true))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var fullContent strings.Builder
+	var toolCalls []ToolCall
+	toolIndex := make(map[int]int) // content block index -> index in toolCalls
+	usage := &Usage{}
+	var stopReason, model string
+	stopped := false
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue // event: lines repeat the type carried in the data
+		}
+		var event anthropicEvent
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+			continue
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				usage.PromptTokens = event.Message.Usage.InputTokens
+				model = event.Message.Model
+			}
+		case "content_block_start":
+			if b := event.ContentBlock; b != nil && b.Type == "tool_use" {
+				toolIndex[event.Index] = len(toolCalls)
+				toolCalls = append(toolCalls, ToolCall{ID: b.ID, Type: "function", Function: FunctionCall{Name: b.Name}})
+			}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				fullContent.WriteString(event.Delta.Text)
+				if onChunk != nil && event.Delta.Text != "" {
+					onChunk(event.Delta.Text)
+				}
+			case "input_json_delta":
+				if i, ok := toolIndex[event.Index]; ok {
+					toolCalls[i].Function.Arguments += event.Delta.PartialJSON
+				}
+			}
+		case "message_delta":
+			if event.Delta.StopReason != "" {
+				stopReason = event.Delta.StopReason
+			}
+			if event.Usage != nil {
+				usage.CompletionTokens = event.Usage.OutputTokens
+			}
+		case "error":
+			if event.Error != nil {
+				return nil, fmt.Errorf("anthropic stream error %s: %s", event.Error.Type, event.Error.Message)
+			}
+		}
+		if event.Type == "message_stop" {
+			stopped = true
+			break
+		}
+	}
+	for i := range toolCalls {
+		if toolCalls[i].Function.Arguments == "" {
+			toolCalls[i].Function.Arguments = "{}"
+		}
+	}
+
+	result := &Completion{
+		Content:      fullContent.String(),
+		ToolCalls:    toolCalls,
+		FinishReason: anthropicFinishReason(stopReason),
+		Usage:        usage,
+		Model:        model,
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("reading stream: %w", err)
+	}
+	if !stopped {
+		return result, errors.New("stream ended before message_stop")
+	}
+	return result, nil
+}
+
+// anthropicFinishReason maps a stop_reason to the OpenAI finish_reason vocabulary.
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "":
+		return ""
+	default:
+		return "stop"
+	}
+}
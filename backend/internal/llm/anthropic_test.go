@@ -0,0 +1,234 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestAnthropic starts a stand-in Messages API served by handler.
+func newTestAnthropic(t *testing.T, handler http.HandlerFunc) *Anthropic {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	cfg := DefaultConfig()
+	cfg.BaseURL = srv.URL + "/v1"
+	cfg.Model = "claude-test"
+	cfg.APIKey = "secret"
+	cfg.Retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	return NewAnthropic(cfg)
+}
+
+// writeSSE writes events as a Messages API event stream.
+func writeSSE(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, e := range events {
+		var typ struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal([]byte(e), &typ)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ.Type, e)
+	}
+}
+
+func TestAnthropicRequestAndResponse(t *testing.T) {
+	var got anthropicRequest
+	a := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if r.Header.Get("X-Api-Key") != "secret" || r.Header.Get("Anthropic-Version") != anthropicVersion {
+			t.Errorf("headers = %v", r.Header)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"model":"claude-test-1","stop_reason":"tool_use",
+			"content":[{"type":"text","text":"Let me check."},{"type":"tool_use","id":"tu_2","name":"calculate","input":{"expression":"1+1"}}],
+			"usage":{"input_tokens":42,"output_tokens":9}}`)
+	})
+
+	messages := []ChatMessage{
+		{Role: "system", Content: "You are Alice."},
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "What is 2*3 and 4*5?"},
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "tu_1a", Type: "function", Function: FunctionCall{Name: "calculate", Arguments: `{"expression":"2*3"}`}},
+			{ID: "tu_1b", Type: "function", Function: FunctionCall{Name: "calculate", Arguments: `not json`}},
+		}},
+		{Role: "tool", ToolCallID: "tu_1a", Content: "6"},
+		{Role: "tool", ToolCallID: "tu_1b", Content: "20"},
+	}
+	tools := []Tool{{Type: "function", Function: FunctionDef{Name: "calculate", Description: "Evaluate", Parameters: map[string]any{"type": "object"}}}}
+	result, err := a.ChatCompletion(context.Background(), messages, Options{Tools: tools, MaxTokens: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.System != "You are Alice.\n\nBe brief." {
+		t.Errorf("system = %q", got.System)
+	}
+	if got.Model != "claude-test" || got.MaxTokens != 100 || got.Stream {
+		t.Errorf("model, max_tokens, stream = %q, %d, %v", got.Model, got.MaxTokens, got.Stream)
+	}
+	if len(got.Tools) != 1 || got.Tools[0].Name != "calculate" || got.Tools[0].InputSchema["type"] != "object" {
+		t.Errorf("tools = %+v", got.Tools)
+	}
+	if len(got.Messages) != 3 {
+		t.Fatalf("messages = %+v", got.Messages)
+	}
+	use := got.Messages[1]
+	if use.Role != "assistant" || len(use.Content) != 2 || use.Content[0].Type != "tool_use" || use.Content[0].ID != "tu_1a" ||
+		string(use.Content[0].Input) != `{"expression":"2*3"}` || string(use.Content[1].Input) != "{}" {
+		t.Errorf("tool_use message = %+v", use)
+	}
+	results := got.Messages[2]
+	want := []anthropicBlock{{Type: "tool_result", ToolUseID: "tu_1a", Content: "6"}, {Type: "tool_result", ToolUseID: "tu_1b", Content: "20"}}
+	if results.Role != "user" || !reflect.DeepEqual(results.Content, want) {
+		t.Errorf("tool_result message = %+v", results)
+	}
+
+	if result.Content != "Let me check." || result.FinishReason != "tool_calls" || result.Model != "claude-test-1" {
+		t.Errorf("result = %+v", result)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].ID != "tu_2" || result.ToolCalls[0].Function.Arguments != `{"expression":"1+1"}` {
+		t.Errorf("tool calls = %+v", result.ToolCalls)
+	}
+	if result.Usage == nil || *result.Usage != (Usage{PromptTokens: 42, CompletionTokens: 9}) {
+		t.Errorf("usage = %+v", result.Usage)
+	}
+}
+
+func TestAnthropicStream(t *testing.T) {
+	a := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			t.Error("stream not requested")
+		}
+		writeSSE(w,
+			`{"type":"message_start","message":{"model":"claude-test-1","content":[],"usage":{"input_tokens":30,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello, "}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"world."}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"tu_1","name":"calculate","input":{}}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"expression\":"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"2+2\"}"}}`,
+			`{"type":"content_block_stop","index":1}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":17}}`,
+			`{"type":"message_stop"}`,
+		)
+	})
+
+	var chunks []string
+	result, err := a.ChatCompletionStream(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, Options{}, func(delta string) {
+		chunks = append(chunks, delta)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(chunks, []string{"Hello, ", "world."}) || result.Content != "Hello, world." {
+		t.Errorf("chunks = %q, content = %q", chunks, result.Content)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].ID != "tu_1" || result.ToolCalls[0].Function.Arguments != `{"expression":"2+2"}` {
+		t.Errorf("tool calls = %+v", result.ToolCalls)
+	}
+	if result.FinishReason != "tool_calls" || result.Model != "claude-test-1" {
+		t.Errorf("finish reason, model = %q, %q", result.FinishReason, result.Model)
+	}
+	if result.Usage == nil || *result.Usage != (Usage{PromptTokens: 30, CompletionTokens: 17}) {
+		t.Errorf("usage = %+v", result.Usage)
+	}
+}
+
+func TestAnthropicStreamError(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		events   []string
+		requests int32
+	}{
+		{
+			name:     "before output",
+			events:   []string{`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`},
+			requests: 3,
+		},
+		{
+			name: "after output",
+			events: []string{
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`,
+				`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			},
+			requests: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests atomic.Int32
+			a := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				writeSSE(w, tc.events...)
+			})
+			var chunks []string
+			_, err := a.ChatCompletionStream(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, Options{}, func(delta string) {
+				chunks = append(chunks, delta)
+			})
+			if err == nil || !strings.Contains(err.Error(), "overloaded_error: Overloaded") {
+				t.Fatalf("err = %v", err)
+			}
+			if n := requests.Load(); n != tc.requests {
+				t.Errorf("requests = %d, want %d", n, tc.requests)
+			}
+			if tc.requests == 1 && !reflect.DeepEqual(chunks, []string{"Hel"}) {
+				t.Errorf("chunks = %q; the stream must not be resent", chunks)
+			}
+		})
+	}
+}
+
+func TestAnthropicStreamWithoutStop(t *testing.T) {
+	var requests atomic.Int32
+	a := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		writeSSE(w, `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`)
+	})
+
+	var chunks []string
+	_, err := a.ChatCompletionStream(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, Options{}, func(delta string) {
+		chunks = append(chunks, delta)
+	})
+	if err == nil || !strings.Contains(err.Error(), "ended before message_stop") {
+		t.Fatalf("err = %v", err)
+	}
+	if n := requests.Load(); n != 1 || !reflect.DeepEqual(chunks, []string{"Hel"}) {
+		t.Errorf("requests = %d, chunks = %q; the stream must not be resent", n, chunks)
+	}
+
+	// Without a listener nothing was delivered, so the request is retried.
+	requests.Store(0)
+	if _, err := a.ChatCompletionStream(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, Options{}, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("requests = %d, want 3", n)
+	}
+}
+
+func TestAnthropicAPIError(t *testing.T) {
+	a := newTestAnthropic(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"type":"error","error":{"type":"invalid_request_error"}}`, http.StatusBadRequest)
+	})
+	_, err := a.ChatCompletion(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, Options{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Backend != "anthropic" {
+		t.Fatalf("err = %v", err)
+	}
+	if IsRetryable(err) {
+		t.Error("a bad request must not be retried")
+	}
+}
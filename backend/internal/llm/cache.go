@@ -0,0 +1,255 @@
+package llm
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheConfig configures a Cache. Zero limits are unlimited.
+type CacheConfig struct {
+	Model       string        // used in the key when Options.Model is empty
+	TTL         time.Duration // entries older than this are dropped
+	MaxBytes    int64         // total size of the entries; the oldest are evicted first
+	Force       bool          // cache requests whose temperature is above zero or unset
+	StreamDelay time.Duration // pause between the deltas of a replayed stream
+}
+
+// Cache stores LLM completions on disk, one file per request, so that identical
+// requests are answered without calling the backend. Attach it with WithCache.
+// Only deterministic requests are cached: those with a temperature of zero, unless
+// Force is set. An unset temperature means the backend's default, which is not zero.
+type Cache struct {
+	dir string
+	cfg CacheConfig
+
+	mu      sync.Mutex
+	entries map[string]cacheFile // by key
+	size    int64
+}
+
+type cacheFile struct {
+	size    int64
+	created time.Time
+}
+
+// cacheEntry is the file stored for one request.
+type cacheEntry struct {
+	Model      string     `json:"model"`
+	Completion Completion `json:"completion"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NewCache opens the cache in dir, creating it if needed and dropping expired entries.
+func NewCache(dir string, cfg CacheConfig) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read cache dir: %w", err)
+	}
+	c := &Cache{dir: dir, cfg: cfg, entries: make(map[string]cacheFile)}
+	for _, f := range files {
+		key, ok := strings.CutSuffix(f.Name(), ".json")
+		if !ok || f.IsDir() {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		c.entries[key] = cacheFile{size: info.Size(), created: info.ModTime()}
+		c.size += info.Size()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, f := range c.entries {
+		if c.expired(f) {
+			c.remove(key)
+		}
+	}
+	c.evict()
+	return c, nil
+}
+
+// WithCache wraps p so that cacheable calls are answered from c when possible and
+// stored in it otherwise. Replayed completions report zero usage, since no tokens
+// were spent on them.
+func WithCache(p Provider, c *Cache) Provider {
+	cp := cacheProvider{Provider: p, cache: c}
+	if lister, ok := p.(ModelLister); ok {
+		return cacheLister{cp, lister}
+	}
+	return cp
+}
+
+type cacheProvider struct {
+	Provider
+	cache *Cache
+}
+
+type cacheLister struct {
+	cacheProvider
+	ModelLister
+}
+
+func (p cacheProvider) ChatCompletion(ctx context.Context, messages []ChatMessage, opts Options) (*Completion, error) {
+	key, ok := p.cache.key(messages, opts)
+	if !ok {
+		return p.Provider.ChatCompletion(ctx, messages, opts)
+	}
+	if result := p.cache.get(key); result != nil {
+		return result, nil
+	}
+	result, err := p.Provider.ChatCompletion(ctx, messages, opts)
+	if err == nil {
+		p.cache.put(key, *result)
+	}
+	return result, err
+}
+
+// ChatCompletionStream replays a cached reply to onChunk word by word, pausing
+// StreamDelay between deltas.
+func (p cacheProvider) ChatCompletionStream(ctx context.Context, messages []ChatMessage, opts Options, onChunk func(delta string)) (*Completion, error) {
+	key, ok := p.cache.key(messages, opts)
+	if !ok {
+		return p.Provider.ChatCompletionStream(ctx, messages, opts, onChunk)
+	}
+	if result := p.cache.get(key); result != nil {
+		if onChunk != nil {
+			for i, delta := range strings.SplitAfter(result.Content, " ") {
+				if i > 0 && p.cache.cfg.StreamDelay > 0 {
+					select {
+					case <-ctx.Done():
+						return nil, ctx.Err()
+					case <-time.After(p.cache.cfg.StreamDelay):
+					}
+				}
+				if delta != "" {
+					onChunk(delta)
+				}
+			}
+		}
+		return result, nil
+	}
+	result, err := p.Provider.ChatCompletionStream(ctx, messages, opts, onChunk)
+	if err == nil {
+		p.cache.put(key, *result)
+	}
+	return result, err
+}
+
+// key hashes the parts of a request that decide its reply. ok is false if the request
+// must not be cached. Streaming and non-streaming calls share entries.
+func (c *Cache) key(messages []ChatMessage, opts Options) (key string, ok bool) {
+	t := opts.Sampling.Temperature
+	if !c.cfg.Force && (t == nil || *t > 0) {
+		return "", false
+	}
+	req := cassetteRequest(messages, opts, false)
+	req.Model = cmp.Or(req.Model, c.cfg.Model)
+	data, err := json.Marshal(struct {
+		ChatCompletionRequest
+		ProviderOptions map[string]any `json:"provider_options,omitempty"`
+	}{req, opts.ProviderOptions})
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// get returns the cached completion for key, or nil if there is none or it has expired.
+func (c *Cache) get(key string) *Completion {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if c.expired(f) {
+		c.remove(key)
+		return nil
+	}
+	data, err := os.ReadFile(c.path(key))
+	var entry cacheEntry
+	if err == nil {
+		err = json.Unmarshal(data, &entry)
+	}
+	if err != nil {
+		log.Printf("WARN: LLM cache entry %s unreadable, dropping it: %v", key, err)
+		c.remove(key)
+		return nil
+	}
+	result := entry.Completion
+	result.Usage = &Usage{}
+	return &result
+}
+
+// put stores a completion under key, evicting the oldest entries if the cache is full.
+func (c *Cache) put(key string, result Completion) {
+	data, err := json.MarshalIndent(cacheEntry{Model: result.Model, Completion: result, CreatedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.WriteFile(c.path(key), data, 0644); err != nil {
+		log.Printf("WARN: LLM cache write failed: %v", err)
+		return
+	}
+	if old, ok := c.entries[key]; ok {
+		c.size -= old.size
+	}
+	c.entries[key] = cacheFile{size: int64(len(data)), created: time.Now()}
+	c.size += int64(len(data))
+	c.evict()
+}
+
+func (c *Cache) expired(f cacheFile) bool {
+	return c.cfg.TTL > 0 && time.Since(f.created) > c.cfg.TTL
+}
+
+// evict removes the oldest entries until the cache is within MaxBytes. c.mu must be held.
+func (c *Cache) evict() {
+	if c.cfg.MaxBytes <= 0 || c.size <= c.cfg.MaxBytes {
+		return
+	}
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return c.entries[a].created.Compare(c.entries[b].created)
+	})
+	for _, key := range keys {
+		if c.size <= c.cfg.MaxBytes {
+			break
+		}
+		c.remove(key)
+	}
+}
+
+// remove deletes the entry for key. c.mu must be held.
+func (c *Cache) remove(key string) {
+	if err := os.Remove(c.path(key)); err != nil && !os.IsNotExist(err) {
+		log.Printf("WARN: LLM cache remove failed: %v", err)
+	}
+	c.size -= c.entries[key].size
+	delete(c.entries, key)
+}
@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// ErrCassetteExhausted is returned in replay mode when a request has no recorded response.
+var ErrCassetteExhausted = errors.New("cassette has no more recorded responses")
+
+// Interaction is one recorded request/response pair.
+type Interaction struct {
+	Request  ChatCompletionRequest `json:"request"`
+	Response Completion            `json:"response"`
+}
+
+// Cassette records every LLM interaction made under a context, or replays recorded
+// interactions in order instead of calling the network. Attach it with WithCassette.
+type Cassette struct {
+	mu           sync.Mutex
+	replay       bool
+	interactions []Interaction
+	pos          int
+	divergent    []int
+}
+
+// NewRecorder returns a cassette that records interactions.
+func NewRecorder() *Cassette {
+	return &Cassette{}
+}
+
+// NewReplayer returns a cassette that serves the given interactions in order.
+func NewReplayer(interactions []Interaction) *Cassette {
+	return &Cassette{replay: true, interactions: interactions}
+}
+
+// WithCassettes wraps p so that calls whose context carries a cassette (see WithCassette)
+// are recorded on it or replayed from it.
+func WithCassettes(p Provider) Provider {
+	return cassetteProvider{p}
+}
+
+type cassetteProvider struct {
+	Provider
+}
+
+// cassetteRequest is the provider-independent request recorded for a call.
+func cassetteRequest(messages []ChatMessage, opts Options, stream bool) ChatCompletionRequest {
+	return ChatCompletionRequest{
+		Model:          opts.Model,
+		Messages:       messages,
+		Stream:         stream,
+		MaxTokens:      opts.MaxTokens,
+		ResponseFormat: opts.ResponseFormat,
+		Tools:          opts.Tools,
+		Sampling:       opts.Sampling,
+	}
+}
+
+func (p cassetteProvider) ChatCompletion(ctx context.Context, messages []ChatMessage, opts Options) (*Completion, error) {
+	cas := cassetteFrom(ctx)
+	if cas == nil {
+		return p.Provider.ChatCompletion(ctx, messages, opts)
+	}
+	req := cassetteRequest(messages, opts, false)
+	if cas.replay {
+		return cas.next(req)
+	}
+	result, err := p.Provider.ChatCompletion(ctx, messages, opts)
+	if err == nil {
+		cas.record(req, *result)
+	}
+	return result, err
+}
+
+// ChatCompletionStream passes a replayed reply to onChunk as a single delta.
+func (p cassetteProvider) ChatCompletionStream(ctx context.Context, messages []ChatMessage, opts Options, onChunk func(delta string)) (*Completion, error) {
+	cas := cassetteFrom(ctx)
+	if cas == nil {
+		return p.Provider.ChatCompletionStream(ctx, messages, opts, onChunk)
+	}
+	req := cassetteRequest(messages, opts, true)
+	if cas.replay {
+		result, err := cas.next(req)
+		if err == nil && result.Content != "" && onChunk != nil {
+			onChunk(result.Content)
+		}
+		return result, err
+	}
+	result, err := p.Provider.ChatCompletionStream(ctx, messages, opts, onChunk)
+	if err == nil {
+		cas.record(req, *result)
+	}
+	return result, err
+}
+
+type cassetteKey struct{}
+
+// WithCassette returns a context whose LLM calls go through the cassette.
+func WithCassette(ctx context.Context, c *Cassette) context.Context {
+	return context.WithValue(ctx, cassetteKey{}, c)
+}
+
+func cassetteFrom(ctx context.Context) *Cassette {
+	c, _ := ctx.Value(cassetteKey{}).(*Cassette)
+	return c
+}
+
+// Interactions returns the interactions recorded so far.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.interactions...)
+}
+
+// Divergent returns the indexes of replayed interactions whose request differed from the recording.
+func (c *Cassette) Divergent() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int(nil), c.divergent...)
+}
+
+func (c *Cassette) record(req ChatCompletionRequest, resp Completion) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, Interaction{Request: req, Response: resp})
+}
+
+// next returns the next recorded response, noting a divergence if req differs from the recorded request.
+func (c *Cassette) next(req ChatCompletionRequest) (*Completion, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pos >= len(c.interactions) {
+		return nil, ErrCassetteExhausted
+	}
+	it := c.interactions[c.pos]
+	if !sameRequest(req, it.Request) {
+		c.divergent = append(c.divergent, c.pos)
+	}
+	c.pos++
+	resp := it.Response
+	return &resp, nil
+}
+
+func sameRequest(a, b ChatCompletionRequest) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
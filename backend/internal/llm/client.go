@@ -6,7 +6,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -19,6 +18,10 @@ type Config struct {
 	APIKey    string
 	Timeout   time.Duration
 	MaxTokens int
+	// ResponseFormat reports whether the backend accepts the response_format parameter.
+	ResponseFormat bool
+	Retry          RetryPolicy
+	Limiter        *RateLimiter // shared by every provider using it; nil means no limits
 }
 
 // DefaultConfig returns a default configuration for LM Studio.
@@ -29,6 +32,7 @@ func DefaultConfig() Config {
 		APIKey:    "not-needed",
 		Timeout:   120 * time.Second,
 		MaxTokens: 4096,
+		Retry:     DefaultRetryPolicy(),
 	}
 }
 
@@ -38,6 +42,9 @@ type Client struct {
 	http *http.Client
 }
 
+var _ Provider = (*Client)(nil)
+var _ ModelLister = (*Client)(nil)
+
 // NewClient creates a new LLM client.
 func NewClient(cfg Config) *Client {
 	return &Client{
@@ -48,119 +55,174 @@ func NewClient(cfg Config) *Client {
 	}
 }
 
-// ChatCompletion sends a non-streaming chat completion request and returns the full response text.
-// maxTokens overrides the default if > 0; 0 means omit max_tokens from the request.
-func (c *Client) ChatCompletion(ctx context.Context, messages []ChatMessage, maxTokens int) (string, error) {
-	var lastErr error
-	for attempt := 0; attempt < 2; attempt++ {
-		if attempt > 0 {
-			time.Sleep(5 * time.Second)
-		}
-		result, err := c.doChatCompletion(ctx, messages, maxTokens)
-		if err == nil {
-			return result, nil
-		}
-		lastErr = err
+// model returns the model to request: the per-request override if set, otherwise the configured one.
+func (c *Client) model(opts Options) string {
+	if opts.Model != "" {
+		return opts.Model
 	}
-	return "", fmt.Errorf("chat completion failed after retries: %w", lastErr)
+	return c.cfg.Model
 }
 
-func (c *Client) doChatCompletion(ctx context.Context, messages []ChatMessage, maxTokens int) (string, error) {
+// Capabilities implements Provider. Response formats are supported only if the configuration says so.
+func (c *Client) Capabilities() Capabilities {
+	return Capabilities{
+		Streaming:      true,
+		Tools:          true,
+		ResponseFormat: c.cfg.ResponseFormat,
+		Seed:           true,
+	}
+}
+
+// Models implements ModelLister using the /models endpoint.
+func (c *Client) Models(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.BaseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("LLM", resp)
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	names := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		names = append(names, m.ID)
+	}
+	return names, nil
+}
+
+// ChatCompletion sends a non-streaming chat completion request and returns the full response.
+func (c *Client) ChatCompletion(ctx context.Context, messages []ChatMessage, opts Options) (*Completion, error) {
+	result, attempts, err := c.cfg.send(ctx, messages, opts, false, nil, func(func(string)) (*Completion, error) {
+		return c.doChatCompletion(ctx, messages, opts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("chat completion failed after %d attempts: %w", attempts, err)
+	}
+	return result, nil
+}
+
+// requestBody builds the request body shared by streaming and non-streaming calls.
+func (c *Client) requestBody(messages []ChatMessage, opts Options, stream bool) ChatCompletionRequest {
 	reqBody := ChatCompletionRequest{
-		Model:    c.cfg.Model,
+		Model:    c.model(opts),
 		Messages: messages,
-		Stream:   false,
+		Stream:   stream,
+		Tools:    opts.Tools,
+		Sampling: opts.Sampling,
+	}
+	if opts.MaxTokens > 0 {
+		reqBody.MaxTokens = opts.MaxTokens
+	}
+	if c.cfg.ResponseFormat {
+		reqBody.ResponseFormat = opts.ResponseFormat
 	}
-	if maxTokens > 0 {
-		reqBody.MaxTokens = maxTokens
+	if stream {
+		reqBody.StreamOptions = &StreamOptions{IncludeUsage: true}
 	}
+	return reqBody
+}
 
-	bodyBytes, err := json.Marshal(reqBody)
+func (c *Client) doChatCompletion(ctx context.Context, messages []ChatMessage, opts Options) (*Completion, error) {
+	bodyBytes, err := json.Marshal(c.requestBody(messages, opts, false))
 	if err != nil {
-		return "", fmt.Errorf("marshal request: %w", err)
+		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL+"/chat/completions", bytes.NewReader(bodyBytes))
 	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
+		return nil, fmt.Errorf("create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
 
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("do request: %w", err)
+		return nil, fmt.Errorf("do request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("LLM API error %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError("LLM", resp)
 	}
 
 	var chatResp ChatCompletionResponse
 	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-		return "", fmt.Errorf("decode response: %w", err)
+		return nil, fmt.Errorf("decode response: %w", err)
 	}
 
 	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
+		return nil, fmt.Errorf("no choices in response")
+	}
+	choice := chatResp.Choices[0]
+	result := &Completion{
+		Content:   choice.Message.Content,
+		ToolCalls: choice.Message.ToolCalls,
+		Usage:     chatResp.Usage,
+		Model:     chatResp.Model,
+	}
+	if choice.FinishReason != nil {
+		result.FinishReason = *choice.FinishReason
 	}
-	return chatResp.Choices[0].Message.Content, nil
+	return result, nil
 }
 
 // ChatCompletionStream sends a streaming chat completion request and calls onChunk for each text delta.
-// maxTokens overrides the default if > 0; 0 means omit max_tokens from the request.
-func (c *Client) ChatCompletionStream(ctx context.Context, messages []ChatMessage, maxTokens int, onChunk func(delta string)) (string, error) {
-	var lastErr error
-	for attempt := 0; attempt < 2; attempt++ {
-		if attempt > 0 {
-			time.Sleep(5 * time.Second)
-		}
-		result, err := c.doChatCompletionStream(ctx, messages, maxTokens, onChunk)
-		if err == nil {
-			return result, nil
-		}
-		lastErr = err
+// Tool call fragments are assembled and returned in the completion, not passed to onChunk.
+func (c *Client) ChatCompletionStream(ctx context.Context, messages []ChatMessage, opts Options, onChunk func(delta string)) (*Completion, error) {
+	result, attempts, err := c.cfg.send(ctx, messages, opts, true, onChunk, func(onChunk func(string)) (*Completion, error) {
+		return c.doChatCompletionStream(ctx, messages, opts, onChunk)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("streaming chat completion failed after %d attempts: %w", attempts, err)
 	}
-	return "", fmt.Errorf("streaming chat completion failed after retries: %w", lastErr)
+	return result, nil
 }
 
-func (c *Client) doChatCompletionStream(ctx context.Context, messages []ChatMessage, maxTokens int, onChunk func(delta string)) (string, error) {
-	reqBody := ChatCompletionRequest{
-		Model:    c.cfg.Model,
-		Messages: messages,
-		Stream:   true,
-	}
-	if maxTokens > 0 {
-		reqBody.MaxTokens = maxTokens
-	}
-
-	bodyBytes, err := json.Marshal(reqBody)
+func (c *Client) doChatCompletionStream(ctx context.Context, messages []ChatMessage, opts Options, onChunk func(delta string)) (*Completion, error) {
+	bodyBytes, err := json.Marshal(c.requestBody(messages, opts, true))
 	if err != nil {
-		return "", fmt.Errorf("marshal request: %w", err)
+		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL+"/chat/completions", bytes.NewReader(bodyBytes))
 	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
+		return nil, fmt.Errorf("create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
 
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("do request: %w", err)
+		return nil, fmt.Errorf("do request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("LLM API error %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError("LLM", resp)
 	}
 
 	var fullContent strings.Builder
+	var toolCalls []ToolCall
+	var finishReason string
+	var usage *Usage
+	var model string
 	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 	for scanner.Scan() {
 		line := scanner.Text()
 		if !strings.HasPrefix(line, "data: ") {
@@ -175,20 +237,59 @@ func (c *Client) doChatCompletionStream(ctx context.Context, messages []ChatMess
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			continue
 		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
 		if len(chunk.Choices) > 0 {
-			delta := chunk.Choices[0].Delta.Content
+			choice := chunk.Choices[0]
+			delta := choice.Delta.Content
 			if delta != "" {
 				fullContent.WriteString(delta)
 				if onChunk != nil {
 					onChunk(delta)
 				}
 			}
+			toolCalls = mergeToolCallDeltas(toolCalls, choice.Delta.ToolCalls)
+			if choice.FinishReason != nil {
+				finishReason = *choice.FinishReason
+			}
 		}
 	}
 
+	result := &Completion{
+		Content:      fullContent.String(),
+		ToolCalls:    toolCalls,
+		FinishReason: finishReason,
+		Usage:        usage,
+		Model:        model,
+	}
 	if err := scanner.Err(); err != nil {
-		return fullContent.String(), fmt.Errorf("reading stream: %w", err)
+		return result, fmt.Errorf("reading stream: %w", err)
 	}
 
-	return fullContent.String(), nil
+	return result, nil
+}
+
+// mergeToolCallDeltas appends streamed tool call fragments to the calls assembled so far.
+// A fragment starts a new call when its index is past the end; otherwise its argument
+// text is appended to the call at that index.
+func mergeToolCallDeltas(calls []ToolCall, deltas []ToolCallDelta) []ToolCall {
+	for _, d := range deltas {
+		for d.Index >= len(calls) {
+			calls = append(calls, ToolCall{Type: "function"})
+		}
+		call := &calls[d.Index]
+		if d.ID != "" {
+			call.ID = d.ID
+		}
+		if d.Type != "" {
+			call.Type = d.Type
+		}
+		call.Function.Name += d.Function.Name
+		call.Function.Arguments += d.Function.Arguments
+	}
+	return calls
 }
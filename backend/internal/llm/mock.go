@@ -0,0 +1,279 @@
+package llm
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// MockScheme prefixes an LLM base URL that selects the mock provider; the rest of the URL
+// is the path of its script file, e.g. "mock://./demo.json". "mock://" alone uses no script.
+const MockScheme = "mock://"
+
+// MockScript configures the mock provider's replies.
+type MockScript struct {
+	Seed         uint64     `json:"seed"`           // seeds the choice among Replies
+	TokenDelayMS int        `json:"token_delay_ms"` // pause before each streamed token
+	Rules        []MockRule `json:"rules"`          // the first matching rule answers
+	Replies      []string   `json:"replies"`        // reply templates used when no rule matches
+}
+
+// MockRule answers the calls it matches. Empty conditions match anything. Agent and round
+// are read from the simulation prompt. Reply is a text/template executed with MockCall.
+type MockRule struct {
+	Agent      string        `json:"agent,omitempty"`
+	Round      int           `json:"round,omitempty"`
+	Match      string        `json:"match,omitempty"` // regular expression over all message contents
+	Reply      string        `json:"reply,omitempty"`
+	ToolCall   *FunctionCall `json:"tool_call,omitempty"`   // returned instead of Reply when tools are offered
+	Error      string        `json:"error,omitempty"`       // fail the call with this message
+	Status     int           `json:"status,omitempty"`      // fail the call as an HTTP error response with this status
+	RetryAfter float64       `json:"retry_after,omitempty"` // seconds, sent as Retry-After with Status
+	Times      int           `json:"times,omitempty"`       // apply the rule to this many calls only; 0 means always
+}
+
+// MockCall is the data reply templates are executed with.
+type MockCall struct {
+	Agent  string // "" if the prompt names no agent
+	Round  int    // 0 if the prompt names no round
+	System string // the system message
+	Prompt string // the last user message
+	Model  string
+}
+
+// defaultMockReplies are used when the script has no replies.
+var defaultMockReplies = []string{
+	"{{if .Agent}}{{.Agent}} studies the situation{{else}}The situation is reviewed{{end}} and decides to proceed carefully, gathering more information before committing resources.",
+	"{{if .Agent}}{{.Agent}} takes{{else}}Participants take{{end}} decisive action{{if .Round}} in round {{.Round}}{{end}}, accepting some risk in exchange for a stronger position.",
+	"{{if .Agent}}{{.Agent}} proposes{{else}}A proposal is made for{{end}} a compromise that addresses the main concerns raised so far and invites the others to respond.",
+}
+
+var (
+	mockAgentRe = regexp.MustCompile(`(?m)^(?:Your name|Твоё имя): (.+)$`)
+	mockRoundRe = regexp.MustCompile(`(?:Execute round|Выполни раунд) (\d+)`)
+)
+
+// Mock is a scripted, deterministic Provider that needs no model server, for demos,
+// frontend development and engine tests.
+type Mock struct {
+	script MockScript
+	rules  []*regexp.Regexp // compiled Match of each rule, nil if empty
+
+	mu   sync.Mutex
+	used []int // calls answered by each rule, for MockRule.Times
+}
+
+var _ Provider = (*Mock)(nil)
+var _ ModelLister = (*Mock)(nil)
+
+// NewMock creates a mock provider from a script.
+func NewMock(script MockScript) (*Mock, error) {
+	m := &Mock{script: script, used: make([]int, len(script.Rules))}
+	for i, r := range script.Rules {
+		var re *regexp.Regexp
+		if r.Match != "" {
+			var err error
+			if re, err = regexp.Compile(r.Match); err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i, err)
+			}
+		}
+		m.rules = append(m.rules, re)
+	}
+	if len(m.script.Replies) == 0 {
+		m.script.Replies = defaultMockReplies
+	}
+	return m, nil
+}
+
+// LoadMockScript reads a mock script from a JSON file.
+func LoadMockScript(path string) (MockScript, error) {
+	var script MockScript
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return script, fmt.Errorf("read mock script: %w", err)
+	}
+	if err := json.Unmarshal(data, &script); err != nil {
+		return script, fmt.Errorf("parse mock script: %w", err)
+	}
+	return script, nil
+}
+
+// Capabilities implements Provider.
+func (m *Mock) Capabilities() Capabilities {
+	return Capabilities{Streaming: true, Tools: true, ResponseFormat: true, Seed: true}
+}
+
+// Models implements ModelLister.
+func (m *Mock) Models(ctx context.Context) ([]string, error) {
+	return []string{"mock"}, nil
+}
+
+// ChatCompletion implements Provider.
+func (m *Mock) ChatCompletion(ctx context.Context, messages []ChatMessage, opts Options) (*Completion, error) {
+	return m.complete(messages, opts)
+}
+
+// ChatCompletionStream implements Provider. The reply is streamed word by word with the
+// script's token delay.
+func (m *Mock) ChatCompletionStream(ctx context.Context, messages []ChatMessage, opts Options, onChunk func(delta string)) (*Completion, error) {
+	result, err := m.complete(messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	delay := time.Duration(m.script.TokenDelayMS) * time.Millisecond
+	for _, token := range strings.SplitAfter(result.Content, " ") {
+		if delay > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+		if onChunk != nil && token != "" {
+			onChunk(token)
+		}
+	}
+	return result, nil
+}
+
+func (m *Mock) complete(messages []ChatMessage, opts Options) (*Completion, error) {
+	call := mockCall(messages, opts)
+	var all strings.Builder
+	for _, msg := range messages {
+		all.WriteString(msg.Content)
+		all.WriteString("\n")
+	}
+	lastIsTool := len(messages) > 0 && messages[len(messages)-1].Role == "tool"
+
+	result := &Completion{FinishReason: "stop", Model: "mock"}
+	if opts.Model != "" {
+		result.Model = opts.Model
+	}
+	rule, matched := m.match(call, all.String())
+	switch {
+	case matched && rule.Status != 0:
+		return nil, &APIError{
+			Backend:    "mock",
+			StatusCode: rule.Status,
+			Body:       cmp.Or(rule.Error, http.StatusText(rule.Status)),
+			RetryAfter: time.Duration(rule.RetryAfter * float64(time.Second)),
+		}
+	case matched && rule.Error != "":
+		return nil, errors.New(rule.Error)
+	case matched && rule.ToolCall != nil && len(opts.Tools) > 0 && !lastIsTool:
+		result.ToolCalls = []ToolCall{{ID: "call_1", Type: "function", Function: *rule.ToolCall}}
+		result.FinishReason = "tool_calls"
+	case matched && rule.Reply != "":
+		content, err := renderMock(rule.Reply, call)
+		if err != nil {
+			return nil, err
+		}
+		result.Content = content
+	case opts.ResponseFormat != nil && opts.ResponseFormat.JSONSchema != nil:
+		result.Content = m.schemaReply(opts, call, all.String())
+	default:
+		content, err := renderMock(m.pick(m.script.Replies, all.String(), opts), call)
+		if err != nil {
+			return nil, err
+		}
+		result.Content = content
+	}
+	result.Usage = &Usage{PromptTokens: EstimateTokens(all.String()), CompletionTokens: EstimateTokens(result.Content)}
+	return result, nil
+}
+
+// match returns the first rule matching the call and counts the use.
+func (m *Mock) match(call MockCall, text string) (MockRule, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, r := range m.script.Rules {
+		if r.Agent != "" && r.Agent != call.Agent || r.Round != 0 && r.Round != call.Round {
+			continue
+		}
+		if m.rules[i] != nil && !m.rules[i].MatchString(text) {
+			continue
+		}
+		if r.Times > 0 && m.used[i] >= r.Times {
+			continue
+		}
+		m.used[i]++
+		return r, true
+	}
+	return MockRule{}, false
+}
+
+// pick chooses a template deterministically from the script seed, the request's sampling
+// seed and the prompt, so runs with different seeds diverge.
+func (m *Mock) pick(templates []string, prompt string, opts Options) string {
+	seed := m.script.Seed
+	if opts.Sampling.Seed != nil {
+		seed ^= uint64(*opts.Sampling.Seed)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(prompt))
+	r := rand.New(rand.NewPCG(seed, h.Sum64()))
+	return templates[r.IntN(len(templates))]
+}
+
+// schemaReply builds a JSON object with a picked sentence for every property of the requested schema.
+func (m *Mock) schemaReply(opts Options, call MockCall, prompt string) string {
+	props, _ := opts.ResponseFormat.JSONSchema.Schema["properties"].(map[string]any)
+	obj := make(map[string]string, len(props))
+	for name := range props {
+		text, err := renderMock(m.pick(m.script.Replies, prompt+name, opts), call)
+		if err != nil {
+			text = name
+		}
+		obj[name] = text
+	}
+	data, _ := json.Marshal(obj)
+	return string(data)
+}
+
+func mockCall(messages []ChatMessage, opts Options) MockCall {
+	call := MockCall{Model: opts.Model}
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			call.System = msg.Content
+		case "user":
+			call.Prompt = msg.Content
+			if match := mockRoundRe.FindStringSubmatch(msg.Content); match != nil && call.Round == 0 {
+				call.Round, _ = strconv.Atoi(match[1])
+			}
+		}
+	}
+	if match := mockAgentRe.FindStringSubmatch(call.System); match != nil {
+		call.Agent = strings.TrimSpace(match[1])
+	}
+	if call.Round == 0 {
+		if match := mockRoundRe.FindStringSubmatch(call.System); match != nil {
+			call.Round, _ = strconv.Atoi(match[1])
+		}
+	}
+	return call
+}
+
+func renderMock(text string, call MockCall) (string, error) {
+	tmpl, err := template.New("reply").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("mock reply template: %w", err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, call); err != nil {
+		return "", fmt.Errorf("mock reply template: %w", err)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,283 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultOllamaBaseURL is the address of a local Ollama server.
+const DefaultOllamaBaseURL = "http://localhost:11434"
+
+// Ollama is a Provider for Ollama's native /api/chat endpoint. Unlike its OpenAI shim it
+// accepts every model option, which callers pass in Options.ProviderOptions.
+// Config.BaseURL is the server root, without /api.
+type Ollama struct {
+	cfg  Config
+	http *http.Client
+}
+
+var _ Provider = (*Ollama)(nil)
+var _ ModelLister = (*Ollama)(nil)
+
+// NewOllama creates a new Ollama provider.
+func NewOllama(cfg Config) *Ollama {
+	return &Ollama{
+		cfg: cfg,
+		http: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+	}
+}
+
+// ollamaRequest is the request body of POST /api/chat.
+type ollamaRequest struct {
+	Model     string          `json:"model"`
+	Messages  []ollamaMessage `json:"messages"`
+	Stream    bool            `json:"stream"`
+	Tools     []Tool          `json:"tools,omitempty"`
+	Format    any             `json:"format,omitempty"` // "json" or a JSON schema
+	Options   map[string]any  `json:"options,omitempty"`
+	KeepAlive any             `json:"keep_alive,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // "tool" messages only
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"` // a JSON object, not a string
+	} `json:"function"`
+}
+
+// ollamaResponse is the non-streaming response and each line of a streaming one.
+type ollamaResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// Capabilities implements Provider.
+func (o *Ollama) Capabilities() Capabilities {
+	return Capabilities{Streaming: true, Tools: true, ResponseFormat: true, Seed: true}
+}
+
+// Models implements ModelLister using /api/tags.
+func (o *Ollama) Models(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.cfg.BaseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("ollama", resp)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	names := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		names = append(names, m.Name)
+	}
+	return names, nil
+}
+
+// ChatCompletion implements Provider.
+func (o *Ollama) ChatCompletion(ctx context.Context, messages []ChatMessage, opts Options) (*Completion, error) {
+	result, attempts, err := o.cfg.send(ctx, messages, opts, false, nil, func(func(string)) (*Completion, error) {
+		return o.doChat(ctx, messages, opts, nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ollama chat failed after %d attempts: %w", attempts, err)
+	}
+	return result, nil
+}
+
+// ChatCompletionStream implements Provider.
+func (o *Ollama) ChatCompletionStream(ctx context.Context, messages []ChatMessage, opts Options, onChunk func(delta string)) (*Completion, error) {
+	result, attempts, err := o.cfg.send(ctx, messages, opts, true, onChunk, func(onChunk func(string)) (*Completion, error) {
+		if onChunk == nil {
+			onChunk = func(string) {} // a non-nil onChunk selects streaming
+		}
+		return o.doChat(ctx, messages, opts, onChunk)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ollama streaming chat failed after %d attempts: %w", attempts, err)
+	}
+	return result, nil
+}
+
+// requestBody maps Options onto Ollama's request. Sampling parameters and MaxTokens
+// (as num_predict) go into options, where ProviderOptions may override them;
+// a keep_alive provider option is sent at the top level, where Ollama expects it.
+func (o *Ollama) requestBody(messages []ChatMessage, opts Options, stream bool) ollamaRequest {
+	req := ollamaRequest{
+		Model:  o.cfg.Model,
+		Stream: stream,
+		Tools:  opts.Tools,
+	}
+	if opts.Model != "" {
+		req.Model = opts.Model
+	}
+	if f := opts.ResponseFormat; f != nil {
+		if f.JSONSchema != nil {
+			req.Format = f.JSONSchema.Schema
+		} else {
+			req.Format = "json"
+		}
+	}
+
+	options := map[string]any{}
+	s := opts.Sampling
+	if s.Temperature != nil {
+		options["temperature"] = *s.Temperature
+	}
+	if s.TopP != nil {
+		options["top_p"] = *s.TopP
+	}
+	if s.Seed != nil {
+		options["seed"] = *s.Seed
+	}
+	if s.PresencePenalty != nil {
+		options["presence_penalty"] = *s.PresencePenalty
+	}
+	if s.FrequencyPenalty != nil {
+		options["frequency_penalty"] = *s.FrequencyPenalty
+	}
+	if len(s.Stop) > 0 {
+		options["stop"] = s.Stop
+	}
+	if opts.MaxTokens > 0 {
+		options["num_predict"] = opts.MaxTokens
+	}
+	for k, v := range opts.ProviderOptions {
+		if k == "keep_alive" {
+			req.KeepAlive = v
+			continue
+		}
+		options[k] = v
+	}
+	if len(options) > 0 {
+		req.Options = options
+	}
+
+	toolNames := make(map[string]string) // tool call ID -> function name
+	for _, m := range messages {
+		msg := ollamaMessage{Role: m.Role, Content: m.Content}
+		for _, call := range m.ToolCalls {
+			toolNames[call.ID] = call.Function.Name
+			var tc ollamaToolCall
+			tc.Function.Name = call.Function.Name
+			tc.Function.Arguments = json.RawMessage(call.Function.Arguments)
+			if !json.Valid(tc.Function.Arguments) {
+				tc.Function.Arguments = json.RawMessage("{}")
+			}
+			msg.ToolCalls = append(msg.ToolCalls, tc)
+		}
+		if m.Role == "tool" {
+			msg.ToolName = toolNames[m.ToolCallID]
+		}
+		req.Messages = append(req.Messages, msg)
+	}
+	return req
+}
+
+// doChat sends one /api/chat request. If onChunk is non-nil the response is streamed as
+// newline-delimited JSON and each content delta is passed to it.
+func (o *Ollama) doChat(ctx context.Context, messages []ChatMessage, opts Options, onChunk func(delta string)) (*Completion, error) {
+	bodyBytes, err := json.Marshal(o.requestBody(messages, opts, onChunk != nil))
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.cfg.BaseURL+"/api/chat", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("ollama", resp)
+	}
+
+	var fullContent strings.Builder
+	result := &Completion{}
+	dec := json.NewDecoder(resp.Body) // reads NDJSON lines and a single object alike
+	for {
+		var chunk ollamaResponse
+		if err := dec.Decode(&chunk); err == io.EOF {
+			result.Content = fullContent.String()
+			return result, errors.New("response ended before done")
+		} else if err != nil {
+			result.Content = fullContent.String()
+			return result, fmt.Errorf("decode response: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("ollama error: %s", chunk.Error)
+		}
+
+		if delta := chunk.Message.Content; delta != "" {
+			fullContent.WriteString(delta)
+			if onChunk != nil {
+				onChunk(delta)
+			}
+		}
+		for _, call := range chunk.Message.ToolCalls {
+			result.ToolCalls = append(result.ToolCalls, ToolCall{
+				ID:       fmt.Sprintf("call_%d", len(result.ToolCalls)+1),
+				Type:     "function",
+				Function: FunctionCall{Name: call.Function.Name, Arguments: string(call.Function.Arguments)},
+			})
+		}
+		if chunk.Done {
+			result.FinishReason = ollamaFinishReason(chunk.DoneReason, len(result.ToolCalls) > 0)
+			result.Usage = &Usage{PromptTokens: chunk.PromptEvalCount, CompletionTokens: chunk.EvalCount}
+			result.Model = chunk.Model
+			break
+		}
+	}
+	result.Content = fullContent.String()
+	return result, nil
+}
+
+// ollamaFinishReason maps a done_reason to the OpenAI finish_reason vocabulary.
+func ollamaFinishReason(doneReason string, toolCalls bool) string {
+	switch {
+	case toolCalls:
+		return "tool_calls"
+	case doneReason == "length":
+		return "length"
+	default:
+		return "stop"
+	}
+}
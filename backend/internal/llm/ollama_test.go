@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestOllama starts a stand-in Ollama server served by handler.
+func newTestOllama(t *testing.T, handler http.HandlerFunc) *Ollama {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	cfg := DefaultConfig()
+	cfg.BaseURL = srv.URL
+	cfg.Model = "llama3"
+	cfg.Retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	return NewOllama(cfg)
+}
+
+func TestOllamaRequestOptions(t *testing.T) {
+	var got map[string]any
+	o := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"model":"llama3","message":{"role":"assistant","content":"ok"},"done":true,"done_reason":"stop","prompt_eval_count":11,"eval_count":2}`)
+	})
+
+	temperature := 0.3
+	result, err := o.ChatCompletion(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, Options{
+		MaxTokens:       64,
+		Sampling:        Sampling{Temperature: &temperature},
+		ProviderOptions: map[string]any{"num_ctx": 8192, "keep_alive": "10m"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["stream"] != false || got["keep_alive"] != "10m" {
+		t.Errorf("stream, keep_alive = %v, %v", got["stream"], got["keep_alive"])
+	}
+	want := map[string]any{"num_ctx": 8192.0, "num_predict": 64.0, "temperature": 0.3}
+	if !reflect.DeepEqual(got["options"], want) {
+		t.Errorf("options = %v, want %v", got["options"], want)
+	}
+	if result.Content != "ok" || result.FinishReason != "stop" || result.Model != "llama3" {
+		t.Errorf("result = %+v", result)
+	}
+	if result.Usage == nil || *result.Usage != (Usage{PromptTokens: 11, CompletionTokens: 2}) {
+		t.Errorf("usage = %+v", result.Usage)
+	}
+}
+
+func TestOllamaStream(t *testing.T) {
+	o := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			t.Error("stream not requested")
+		}
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":"Hello, "},"done":false}`)
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":"world."},"done":false}`)
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"calculate","arguments":{"expression":"2+2"}}}]},"done":false}`)
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":20,"eval_count":5}`)
+	})
+
+	var chunks []string
+	result, err := o.ChatCompletionStream(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, Options{}, func(delta string) {
+		chunks = append(chunks, delta)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(chunks, []string{"Hello, ", "world."}) || result.Content != "Hello, world." {
+		t.Errorf("chunks = %q, content = %q", chunks, result.Content)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].Function.Arguments != `{"expression":"2+2"}` || result.FinishReason != "tool_calls" {
+		t.Errorf("tool calls = %+v, finish reason = %q", result.ToolCalls, result.FinishReason)
+	}
+	if result.Usage == nil || *result.Usage != (Usage{PromptTokens: 20, CompletionTokens: 5}) {
+		t.Errorf("usage = %+v", result.Usage)
+	}
+}
+
+func TestOllamaStreamWithoutDone(t *testing.T) {
+	var requests atomic.Int32
+	o := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprintln(w, `{"model":"llama3","message":{"role":"assistant","content":"Hel"},"done":false}`)
+	})
+
+	var chunks []string
+	_, err := o.ChatCompletionStream(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, Options{}, func(delta string) {
+		chunks = append(chunks, delta)
+	})
+	if err == nil || !strings.Contains(err.Error(), "ended before done") {
+		t.Fatalf("err = %v", err)
+	}
+	if n := requests.Load(); n != 1 || !reflect.DeepEqual(chunks, []string{"Hel"}) {
+		t.Errorf("requests = %d, chunks = %q; the stream must not be resent", n, chunks)
+	}
+
+	// Without a listener nothing was delivered, so the request is retried.
+	requests.Store(0)
+	if _, err := o.ChatCompletionStream(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, Options{}, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("requests = %d, want 3", n)
+	}
+}
+
+func TestOllamaModels(t *testing.T) {
+	o := newTestOllama(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"models":[{"name":"llama3:latest","size":1},{"name":"qwen2.5:7b","size":2}]}`)
+	})
+	names, err := o.Models(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"llama3:latest", "qwen2.5:7b"}) {
+		t.Errorf("models = %q", names)
+	}
+}
@@ -0,0 +1,302 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Balance strategies of a Pool.
+const (
+	BalanceRoundRobin    = "round_robin"     // take endpoints in turn (default)
+	BalanceLeastInFlight = "least_in_flight" // take the endpoint with the fewest requests running
+)
+
+// ErrNoEndpoint is returned when every endpoint serving a model is ejected. It is
+// retryable: ejected endpoints are probed again once their cooldown is over.
+var ErrNoEndpoint = errors.New("no LLM endpoint available")
+
+// PoolEndpoint is one backend of a Pool.
+type PoolEndpoint struct {
+	Name     string   // shown in logs and status, usually the base URL
+	Provider Provider // should make a single attempt; the pool retries across endpoints
+	Models   []string // models served; empty means every model
+}
+
+// PoolConfig configures a Pool.
+type PoolConfig struct {
+	Model            string        // used to pick endpoints when Options.Model is empty
+	Balance          string        // BalanceRoundRobin or BalanceLeastInFlight
+	FailureThreshold int           // consecutive failures that eject an endpoint
+	Cooldown         time.Duration // before an ejected endpoint is probed again
+	Retry            RetryPolicy   // attempts over the whole pool
+}
+
+// DefaultPoolConfig returns a round-robin configuration that ejects an endpoint after
+// 3 consecutive failures for 30 seconds.
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		Balance:          BalanceRoundRobin,
+		FailureThreshold: 3,
+		Cooldown:         30 * time.Second,
+		Retry:            DefaultRetryPolicy(),
+	}
+}
+
+// EndpointStatus is the health of one endpoint in a Pool.
+type EndpointStatus struct {
+	Name      string     `json:"name"`
+	Models    []string   `json:"models,omitempty"`
+	InFlight  int        `json:"in_flight"`
+	Failures  int        `json:"failures"`             // consecutive
+	Ejected   bool       `json:"ejected"`              // the circuit breaker is open
+	OpenUntil *time.Time `json:"open_until,omitempty"` // when the next probe may be sent
+}
+
+// Pool spreads requests over several endpoints serving the same models. Each endpoint
+// has a circuit breaker: after FailureThreshold consecutive retryable failures it is
+// ejected, and once Cooldown has passed a single probe request decides whether it
+// returns. A request that fails on one endpoint before any output reached the caller
+// fails over to the next; a stream that breaks midway is not resent.
+type Pool struct {
+	cfg       PoolConfig
+	endpoints []*endpoint
+
+	mu   sync.Mutex
+	next int // round-robin position
+}
+
+type endpoint struct {
+	PoolEndpoint
+	inFlight  int
+	failures  int
+	openUntil time.Time // zero while the endpoint is in service
+	probing   bool      // a probe request is running
+}
+
+// NewPool creates a pool over endpoints.
+func NewPool(cfg PoolConfig, endpoints []PoolEndpoint) (*Pool, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("pool has no endpoints")
+	}
+	switch cfg.Balance {
+	case "":
+		cfg.Balance = BalanceRoundRobin
+	case BalanceRoundRobin, BalanceLeastInFlight:
+	default:
+		return nil, fmt.Errorf("unknown balance strategy %q", cfg.Balance)
+	}
+	if cfg.FailureThreshold < 1 {
+		cfg.FailureThreshold = 1
+	}
+	p := &Pool{cfg: cfg}
+	for _, ep := range endpoints {
+		p.endpoints = append(p.endpoints, &endpoint{PoolEndpoint: ep})
+	}
+	return p, nil
+}
+
+// ChatCompletion sends messages to the next healthy endpoint, failing over on error.
+func (p *Pool) ChatCompletion(ctx context.Context, messages []ChatMessage, opts Options) (*Completion, error) {
+	return p.call(ctx, opts, func(ep Provider) (*Completion, error) {
+		return ep.ChatCompletion(ctx, messages, opts)
+	})
+}
+
+// ChatCompletionStream streams from the next healthy endpoint. It fails over only while
+// no delta has been passed to onChunk.
+func (p *Pool) ChatCompletionStream(ctx context.Context, messages []ChatMessage, opts Options, onChunk func(delta string)) (*Completion, error) {
+	started := false
+	var forward func(string)
+	if onChunk != nil {
+		forward = func(delta string) {
+			started = true
+			onChunk(delta)
+		}
+	}
+	return p.call(ctx, opts, func(ep Provider) (*Completion, error) {
+		result, err := ep.ChatCompletionStream(ctx, messages, opts, forward)
+		if err != nil && started {
+			return nil, &StreamError{Err: err}
+		}
+		return result, err
+	})
+}
+
+// Capabilities reports the features every endpoint supports.
+func (p *Pool) Capabilities() Capabilities {
+	caps := p.endpoints[0].Provider.Capabilities()
+	for _, ep := range p.endpoints[1:] {
+		c := ep.Provider.Capabilities()
+		caps.Streaming = caps.Streaming && c.Streaming
+		caps.Tools = caps.Tools && c.Tools
+		caps.ResponseFormat = caps.ResponseFormat && c.ResponseFormat
+		caps.Seed = caps.Seed && c.Seed
+	}
+	return caps
+}
+
+// Models lists the models of every endpoint that can list them. Endpoints that fail
+// are skipped unless all of them do.
+func (p *Pool) Models(ctx context.Context) ([]string, error) {
+	var models []string
+	var lastErr error
+	listed := false
+	for _, ep := range p.endpoints {
+		lister, ok := ep.Provider.(ModelLister)
+		if !ok {
+			continue
+		}
+		names, err := lister.Models(ctx)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", ep.Name, err)
+			continue
+		}
+		listed = true
+		for _, name := range names {
+			if !slices.Contains(models, name) {
+				models = append(models, name)
+			}
+		}
+	}
+	if !listed && lastErr != nil {
+		return nil, lastErr
+	}
+	return models, nil
+}
+
+// Status reports the health of every endpoint. A nil *Pool has none.
+func (p *Pool) Status() []EndpointStatus {
+	if p == nil {
+		return []EndpointStatus{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := make([]EndpointStatus, 0, len(p.endpoints))
+	for _, ep := range p.endpoints {
+		s := EndpointStatus{
+			Name:     ep.Name,
+			Models:   ep.Models,
+			InFlight: ep.inFlight,
+			Failures: ep.failures,
+			Ejected:  !ep.openUntil.IsZero(),
+		}
+		if s.Ejected {
+			openUntil := ep.openUntil
+			s.OpenUntil = &openUntil
+		}
+		status = append(status, s)
+	}
+	return status
+}
+
+// call runs fn under the pool's retry policy. Each attempt tries the candidate
+// endpoints in turn until one succeeds or an error is not worth failing over.
+func (p *Pool) call(ctx context.Context, opts Options, fn func(Provider) (*Completion, error)) (*Completion, error) {
+	model := opts.Model
+	if model == "" {
+		model = p.cfg.Model
+	}
+	result, attempts, err := p.cfg.Retry.do(ctx, func() (*Completion, error) {
+		lastErr := ErrNoEndpoint
+		for _, ep := range p.candidates(model) {
+			ok, probe := p.begin(ep)
+			if !ok {
+				continue
+			}
+			result, err := fn(ep.Provider)
+			p.end(ep, probe, err)
+			if err == nil {
+				return result, nil
+			}
+			lastErr = fmt.Errorf("%s: %w", ep.Name, err)
+			if ctx.Err() != nil || !IsRetryable(err) {
+				break
+			}
+		}
+		return nil, lastErr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("request failed after %d attempts: %w", attempts, err)
+	}
+	return result, nil
+}
+
+// candidates returns the endpoints serving model in the order they should be tried.
+// Ejected endpoints are left out until their cooldown is over.
+func (p *Pool) candidates(model string) []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var eps []*endpoint
+	for i := range p.endpoints {
+		ep := p.endpoints[(p.next+i)%len(p.endpoints)]
+		if len(ep.Models) > 0 && !slices.Contains(ep.Models, model) {
+			continue
+		}
+		if !ep.openUntil.IsZero() && (now.Before(ep.openUntil) || ep.probing) {
+			continue
+		}
+		eps = append(eps, ep)
+	}
+	p.next = (p.next + 1) % len(p.endpoints)
+	if p.cfg.Balance == BalanceLeastInFlight {
+		slices.SortStableFunc(eps, func(a, b *endpoint) int { return a.inFlight - b.inFlight })
+	}
+	return eps
+}
+
+// begin marks a request as running on ep. For an ejected endpoint it starts the probe:
+// probe reports whether the request is it, and ok is false if another request got to
+// probe first.
+func (p *Pool) begin(ep *endpoint) (ok, probe bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !ep.openUntil.IsZero() {
+		if ep.probing || time.Now().Before(ep.openUntil) {
+			return false, false
+		}
+		ep.probing, probe = true, true
+		log.Printf("WARN: LLM endpoint %s: probing after cooldown", ep.Name)
+	}
+	ep.inFlight++
+	return true, probe
+}
+
+// end records the outcome of a request on ep; probe is set for the probe request. Only
+// retryable errors count against the endpoint; a bad request would fail anywhere.
+func (p *Pool) end(ep *endpoint, probe bool, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ep.inFlight--
+	if probe {
+		ep.probing = false
+	}
+	var streamErr *StreamError
+	if errors.As(err, &streamErr) {
+		err = streamErr.Err
+	}
+	switch {
+	case err == nil:
+		if !ep.openUntil.IsZero() {
+			log.Printf("LLM endpoint %s is back in service", ep.Name)
+		}
+		ep.failures = 0
+		ep.openUntil = time.Time{}
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+	case IsRetryable(err):
+		ep.failures++
+		if probe || ep.failures >= p.cfg.FailureThreshold {
+			ep.openUntil = time.Now().Add(p.cfg.Cooldown)
+			log.Printf("WARN: LLM endpoint %s ejected for %s after %d consecutive failures: %v", ep.Name, p.cfg.Cooldown, ep.failures, err)
+		}
+	case probe:
+		// The endpoint answered, so it is reachable again.
+		ep.failures = 0
+		ep.openUntil = time.Time{}
+	}
+}
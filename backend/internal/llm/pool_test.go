@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// gatedProvider announces each call on started and answers it with the error sent back.
+type gatedProvider struct {
+	started chan chan error
+}
+
+func (g *gatedProvider) ChatCompletion(ctx context.Context, messages []ChatMessage, opts Options) (*Completion, error) {
+	reply := make(chan error)
+	g.started <- reply
+	if err := <-reply; err != nil {
+		return nil, err
+	}
+	return &Completion{Content: "ok"}, nil
+}
+
+func (g *gatedProvider) ChatCompletionStream(ctx context.Context, messages []ChatMessage, opts Options, onChunk func(string)) (*Completion, error) {
+	return g.ChatCompletion(ctx, messages, opts)
+}
+
+func (g *gatedProvider) Capabilities() Capabilities { return Capabilities{} }
+
+func TestPoolOnlyTheProbeEndsProbing(t *testing.T) {
+	g := &gatedProvider{started: make(chan chan error)}
+	p, err := NewPool(PoolConfig{FailureThreshold: 1, Cooldown: 10 * time.Millisecond, Retry: RetryPolicy{MaxAttempts: 1}},
+		[]PoolEndpoint{{Name: "llm", Provider: g}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func() <-chan error {
+		done := make(chan error, 1)
+		go func() {
+			_, err := p.ChatCompletion(context.Background(), nil, Options{})
+			done <- err
+		}()
+		return done
+	}
+
+	slow := call()
+	slowReply := <-g.started
+	failing := call()
+	(<-g.started) <- &APIError{Backend: "mock", StatusCode: 503}
+	<-failing // the endpoint is ejected; slow is still running on it
+	time.Sleep(20 * time.Millisecond)
+
+	probe := call()
+	probeReply := <-g.started
+	slowReply <- &APIError{Backend: "mock", StatusCode: 400}
+	<-slow
+
+	other := call()
+	select {
+	case err := <-other:
+		if !errors.Is(err, ErrNoEndpoint) {
+			t.Errorf("err = %v, want ErrNoEndpoint", err)
+		}
+	case reply := <-g.started:
+		t.Error("a second request went to the endpoint while its probe was running")
+		reply <- nil
+		<-other
+	}
+	probeReply <- nil
+	if err := <-probe; err != nil {
+		t.Errorf("probe: %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package llm
+
+import "context"
+
+// Provider is an LLM backend. Client is the OpenAI-compatible implementation; wrappers
+// such as WithCassettes add behaviour around any provider.
+type Provider interface {
+	// ChatCompletion sends messages and returns the full reply.
+	ChatCompletion(ctx context.Context, messages []ChatMessage, opts Options) (*Completion, error)
+	// ChatCompletionStream sends messages and calls onChunk for each text delta as it arrives.
+	ChatCompletionStream(ctx context.Context, messages []ChatMessage, opts Options, onChunk func(delta string)) (*Completion, error)
+	// Capabilities reports which optional request features the backend supports.
+	Capabilities() Capabilities
+}
+
+// Capabilities describes the optional features of a provider. Options a provider does not
+// support should be left unset by callers; providers ignore them.
+type Capabilities struct {
+	Streaming      bool `json:"streaming"`       // deltas arrive incrementally rather than in one piece
+	Tools          bool `json:"tools"`           // Options.Tools and tool calls in replies
+	ResponseFormat bool `json:"response_format"` // Options.ResponseFormat
+	Seed           bool `json:"seed"`            // Sampling.Seed
+}
+
+// ModelLister is implemented by providers that can list the models they serve.
+type ModelLister interface {
+	Models(ctx context.Context) ([]string, error)
+}
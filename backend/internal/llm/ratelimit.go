@@ -0,0 +1,288 @@
+package llm
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimits are the limits of one endpoint and model. Zero fields are unlimited.
+type RateLimits struct {
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"`
+	TokensPerMinute   int `json:"tokens_per_minute,omitempty"` // prompt and completion tokens
+	MaxStreams        int `json:"max_streams,omitempty"`       // streaming requests in flight
+}
+
+// RateLimitStatus is the current load of one endpoint and model.
+type RateLimitStatus struct {
+	Endpoint   string     `json:"endpoint"`
+	Model      string     `json:"model"`
+	Limits     RateLimits `json:"limits"`
+	Requests   int        `json:"requests"` // started in the last minute
+	Tokens     int        `json:"tokens"`   // used or reserved in the last minute
+	Streams    int        `json:"streams"`
+	Waiting    int        `json:"waiting"`
+	Saturation float64    `json:"saturation"` // the highest fraction of any limit in use
+}
+
+// RateLimiter holds LLM requests back to stay within rate limits per endpoint and model.
+// Waiting requests are served in turns between callers (see WithCaller), so a busy
+// simulation cannot starve the others. Token counts are estimated when a request is
+// queued and corrected once it returns. A nil *RateLimiter does not limit.
+type RateLimiter struct {
+	defaults RateLimits
+	models   map[string]RateLimits
+
+	mu      sync.Mutex
+	buckets map[limitKey]*bucket
+}
+
+// NewRateLimiter creates a rate limiter applying defaults to every model without an
+// entry in models.
+func NewRateLimiter(defaults RateLimits, models map[string]RateLimits) *RateLimiter {
+	return &RateLimiter{
+		defaults: defaults,
+		models:   models,
+		buckets:  make(map[limitKey]*bucket),
+	}
+}
+
+type limitKey struct {
+	endpoint, model string
+}
+
+// bucket is the state of one endpoint and model.
+type bucket struct {
+	limits   RateLimits
+	requests []time.Time   // start times within the last minute
+	tokens   []*tokenEntry // per request within the last minute, oldest first
+	streams  int
+	queues   map[string][]*waiter // by caller
+	turns    []string             // callers with waiting requests, next first
+	timer    *time.Timer
+}
+
+type tokenEntry struct {
+	at time.Time
+	n  int
+}
+
+type waiter struct {
+	tokens int
+	stream bool
+	ready  chan struct{} // closed when the request may start
+	entry  *tokenEntry
+}
+
+type callerKey struct{}
+
+// WithCaller returns a context whose LLM requests are queued as the given caller's,
+// such as a simulation ID.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+func callerFrom(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// acquire waits until a request of about tokens tokens may be sent to endpoint and
+// model. The returned func must be called with the tokens actually used once the
+// request has finished.
+func (l *RateLimiter) acquire(ctx context.Context, endpoint, model string, tokens int, stream bool) (func(used int), error) {
+	if l == nil {
+		return func(int) {}, nil
+	}
+	l.mu.Lock()
+	key := limitKey{endpoint, model}
+	b := l.buckets[key]
+	if b == nil {
+		limits, ok := l.models[model]
+		if !ok {
+			limits = l.defaults
+		}
+		b = &bucket{limits: limits, queues: make(map[string][]*waiter)}
+		l.buckets[key] = b
+	}
+	caller := callerFrom(ctx)
+	w := &waiter{tokens: tokens, stream: stream, ready: make(chan struct{})}
+	if len(b.queues[caller]) == 0 {
+		b.turns = append(b.turns, caller)
+	}
+	b.queues[caller] = append(b.queues[caller], w)
+	l.dispatch(b)
+	l.mu.Unlock()
+
+	release := func(used int) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		w.entry.n = used
+		if w.stream {
+			b.streams--
+		}
+		l.dispatch(b)
+	}
+
+	select {
+	case <-w.ready:
+		return release, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		select {
+		case <-w.ready: // granted meanwhile
+			l.mu.Unlock()
+			release(0)
+			return nil, ctx.Err()
+		default:
+		}
+		b.remove(caller, w)
+		l.dispatch(b)
+		l.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// dispatch starts waiting requests in turn for as long as the limits allow, and sets a
+// timer for when the next one will fit. A caller whose next request waits for a stream
+// slot keeps its turn but does not hold up the others. l.mu must be held.
+func (l *RateLimiter) dispatch(b *bucket) {
+	now := time.Now()
+	b.prune(now)
+	for i := 0; i < len(b.turns); {
+		caller := b.turns[i]
+		w := b.queues[caller][0]
+		wait, ok := b.fits(w, now)
+		if !ok && wait == 0 {
+			i++ // a finished stream will call dispatch again
+			continue
+		}
+		if !ok {
+			if b.timer != nil {
+				b.timer.Stop()
+			}
+			b.timer = time.AfterFunc(wait, func() {
+				l.mu.Lock()
+				defer l.mu.Unlock()
+				l.dispatch(b)
+			})
+			return
+		}
+
+		b.queues[caller] = b.queues[caller][1:]
+		b.turns = slices.Delete(b.turns, i, i+1)
+		if len(b.queues[caller]) > 0 {
+			b.turns = append(b.turns, caller)
+		} else {
+			delete(b.queues, caller)
+		}
+		w.entry = &tokenEntry{at: now, n: w.tokens}
+		b.requests = append(b.requests, now)
+		b.tokens = append(b.tokens, w.entry)
+		if w.stream {
+			b.streams++
+		}
+		close(w.ready)
+	}
+}
+
+// fits reports whether w may start now; if not, wait is how long until it may, or 0 if
+// it waits for a stream to finish.
+func (b *bucket) fits(w *waiter, now time.Time) (wait time.Duration, ok bool) {
+	lim := b.limits
+	if w.stream && lim.MaxStreams > 0 && b.streams >= lim.MaxStreams {
+		return 0, false
+	}
+	if n := len(b.requests); lim.RequestsPerMinute > 0 && n >= lim.RequestsPerMinute {
+		wait = max(wait, b.requests[n-lim.RequestsPerMinute].Add(time.Minute).Sub(now))
+	}
+	if lim.TokensPerMinute > 0 {
+		used := b.usedTokens()
+		// A request larger than the whole limit goes alone once the window is empty.
+		need := min(w.tokens, lim.TokensPerMinute)
+		for _, e := range b.tokens {
+			if used+need <= lim.TokensPerMinute {
+				break
+			}
+			used -= e.n
+			wait = max(wait, e.at.Add(time.Minute).Sub(now))
+		}
+	}
+	return wait, wait <= 0
+}
+
+// prune drops requests that started more than a minute ago.
+func (b *bucket) prune(now time.Time) {
+	cutoff := now.Add(-time.Minute)
+	i := 0
+	for i < len(b.requests) && !b.requests[i].After(cutoff) {
+		i++
+	}
+	b.requests = b.requests[i:]
+	i = 0
+	for i < len(b.tokens) && !b.tokens[i].at.After(cutoff) {
+		i++
+	}
+	b.tokens = b.tokens[i:]
+}
+
+func (b *bucket) usedTokens() int {
+	used := 0
+	for _, e := range b.tokens {
+		used += e.n
+	}
+	return used
+}
+
+// remove takes a waiter that gave up out of the caller's queue.
+func (b *bucket) remove(caller string, w *waiter) {
+	q := slices.DeleteFunc(b.queues[caller], func(x *waiter) bool { return x == w })
+	if len(q) > 0 {
+		b.queues[caller] = q
+		return
+	}
+	delete(b.queues, caller)
+	b.turns = slices.DeleteFunc(b.turns, func(c string) bool { return c == caller })
+}
+
+// Status returns the current load of every endpoint and model used so far.
+func (l *RateLimiter) Status() []RateLimitStatus {
+	if l == nil {
+		return []RateLimitStatus{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	status := make([]RateLimitStatus, 0, len(l.buckets))
+	for key, b := range l.buckets {
+		b.prune(now)
+		s := RateLimitStatus{
+			Endpoint: key.endpoint,
+			Model:    key.model,
+			Limits:   b.limits,
+			Requests: len(b.requests),
+			Tokens:   b.usedTokens(),
+			Streams:  b.streams,
+		}
+		for _, q := range b.queues {
+			s.Waiting += len(q)
+		}
+		for _, f := range []struct{ used, limit int }{
+			{s.Requests, b.limits.RequestsPerMinute},
+			{s.Tokens, b.limits.TokensPerMinute},
+			{s.Streams, b.limits.MaxStreams},
+		} {
+			if f.limit > 0 {
+				s.Saturation = max(s.Saturation, float64(f.used)/float64(f.limit))
+			}
+		}
+		status = append(status, s)
+	}
+	slices.SortFunc(status, func(a, b RateLimitStatus) int {
+		return cmp.Or(strings.Compare(a.Endpoint, b.Endpoint), strings.Compare(a.Model, b.Model))
+	})
+	return status
+}
@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterStreamWaitDoesNotBlockOthers(t *testing.T) {
+	l := NewRateLimiter(RateLimits{MaxStreams: 1}, nil)
+	a := WithCaller(context.Background(), "a")
+	b := WithCaller(context.Background(), "b")
+
+	release, err := l.acquire(a, "http://llm", "m", 10, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a's second stream waits for the slot at the head of the turns.
+	streamed := make(chan struct{})
+	go func() {
+		release, err := l.acquire(a, "http://llm", "m", 10, true)
+		if err == nil {
+			release(10)
+		}
+		close(streamed)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		release, err := l.acquire(b, "http://llm", "m", 10, false)
+		if err == nil {
+			release(10)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a request that fits waited behind one blocked on a stream slot")
+	}
+
+	select {
+	case <-streamed:
+		t.Fatal("second stream started while the slot was taken")
+	default:
+	}
+	release(10)
+	select {
+	case <-streamed:
+	case <-time.After(time.Second):
+		t.Fatal("second stream did not start once the slot was free")
+	}
+}
@@ -0,0 +1,189 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how providers retry failed requests.
+type RetryPolicy struct {
+	MaxAttempts int           // attempts in total, including the first; values below 1 mean 1
+	BaseDelay   time.Duration // wait before the first retry; doubles with each further retry
+	MaxDelay    time.Duration // longest single wait; a longer Retry-After ends the retries
+	Jitter      float64       // the wait varies randomly by up to this fraction, 0 to 1
+}
+
+// DefaultRetryPolicy returns the retry policy of DefaultConfig.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// APIError is an unsuccessful HTTP response from an LLM backend.
+type APIError struct {
+	Backend    string // "LLM", "anthropic", "ollama" or "mock", for the message
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // as asked by a Retry-After header, 0 if there was none
+}
+
+func (e *APIError) Error() string {
+	return e.Backend + " API error " + strconv.Itoa(e.StatusCode) + ": " + e.Body
+}
+
+// Retryable reports whether the request may succeed if sent again: on timeouts, rate
+// limits and server errors, but not on other client errors such as a bad request.
+func (e *APIError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	case http.StatusNotImplemented, http.StatusHTTPVersionNotSupported:
+		return false
+	}
+	return e.StatusCode >= 500
+}
+
+// newAPIError reads an unsuccessful response into an APIError.
+func newAPIError(backend string, resp *http.Response) *APIError {
+	body, _ := io.ReadAll(resp.Body)
+	return &APIError{
+		Backend:    backend,
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
+
+// IsRetryable reports whether a failed request may succeed if sent again. API errors
+// are classified by status code; cancellations and streams broken after output was
+// delivered are final; other failures, such as network errors, are assumed to be transient.
+func IsRetryable(err error) bool {
+	var streamErr *StreamError
+	if errors.Is(err, context.Canceled) || errors.As(err, &streamErr) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+	return true
+}
+
+// do calls fn until it succeeds, fails with an error that is not retryable, or the
+// attempts are used up, and returns the number of attempts made. Between attempts it
+// waits with exponential backoff and jitter, or as long as a Retry-After header asks,
+// and it stops waiting when ctx is done.
+func (p RetryPolicy) do(ctx context.Context, fn func() (*Completion, error)) (*Completion, int, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := fn()
+		if err == nil {
+			return result, attempt, nil
+		}
+		if attempt >= p.MaxAttempts || ctx.Err() != nil || !IsRetryable(err) {
+			return nil, attempt, err
+		}
+
+		delay := p.delay(attempt)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			if p.MaxDelay > 0 && apiErr.RetryAfter > p.MaxDelay {
+				return nil, attempt, err
+			}
+			delay = apiErr.RetryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return nil, attempt, err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// delay returns the backoff before retry number attempt.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d += time.Duration(float64(d) * p.Jitter * (2*rand.Float64() - 1))
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// send makes a request under the configured retry policy, passing every attempt through
+// the rate limiter. fn makes one attempt, passing deltas to the onChunk it is given. Once
+// a delta has reached onChunk a failure is returned as a StreamError and not retried.
+func (cfg Config) send(ctx context.Context, messages []ChatMessage, opts Options, stream bool, onChunk func(delta string), fn func(onChunk func(delta string)) (*Completion, error)) (*Completion, int, error) {
+	model := opts.Model
+	if model == "" {
+		model = cfg.Model
+	}
+	tokens := EstimateUsage(messages, &Completion{}).PromptTokens + opts.MaxTokens
+	delivered := false
+	var forward func(string)
+	if onChunk != nil {
+		forward = func(delta string) {
+			delivered = true
+			onChunk(delta)
+		}
+	}
+	return cfg.Retry.do(ctx, func() (*Completion, error) {
+		release, err := cfg.Limiter.acquire(ctx, cfg.BaseURL, model, tokens, stream)
+		if err != nil {
+			return nil, err
+		}
+		result, err := fn(forward)
+		if err != nil && delivered {
+			err = &StreamError{Err: err}
+		}
+		used := tokens
+		if result != nil {
+			u := EstimateUsage(messages, result)
+			if result.Usage != nil {
+				u = *result.Usage
+			}
+			used = u.PromptTokens + u.CompletionTokens
+		}
+		release(used)
+		return result, err
+	})
+}
+
+// StreamError is a stream that broke after part of the reply was delivered. It is not
+// retried, since the caller has already seen the output.
+type StreamError struct {
+	Err error
+}
+
+func (e *StreamError) Error() string {
+	return "stream interrupted: " + e.Err.Error()
+}
+
+func (e *StreamError) Unwrap() error { return e.Err }
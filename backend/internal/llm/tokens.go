@@ -0,0 +1,30 @@
+package llm
+
+import "unicode/utf8"
+
+// EstimateTokens returns a rough token count for text, for use when the server reports none.
+// It uses the common rule of thumb of about four characters per token.
+func EstimateTokens(text string) int {
+	n := utf8.RuneCountInString(text)
+	if n == 0 {
+		return 0
+	}
+	return (n + 3) / 4
+}
+
+// EstimateUsage estimates the usage of a call from its messages and reply, for use when
+// the server reports none.
+func EstimateUsage(messages []ChatMessage, c *Completion) Usage {
+	var u Usage
+	for _, m := range messages {
+		u.PromptTokens += EstimateTokens(m.Content)
+		for _, call := range m.ToolCalls {
+			u.PromptTokens += EstimateTokens(call.Function.Name + call.Function.Arguments)
+		}
+	}
+	u.CompletionTokens = EstimateTokens(c.Content)
+	for _, call := range c.ToolCalls {
+		u.CompletionTokens += EstimateTokens(call.Function.Name + call.Function.Arguments)
+	}
+	return u
+}
@@ -2,35 +2,148 @@ package llm
 
 // ChatMessage represents a single message in a chat conversation.
 type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // assistant messages only
+	ToolCallID string     `json:"tool_call_id,omitempty"` // "tool" messages only
+}
+
+// Tool describes a function the model may call.
+type Tool struct {
+	Type     string      `json:"type"` // always "function"
+	Function FunctionDef `json:"function"`
+}
+
+// FunctionDef is the name, description and JSON schema of a callable function.
+type FunctionDef struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// ToolCall is a function call requested by the model.
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall holds the called function's name and its JSON-encoded arguments.
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// Completion is the assistant's reply to a chat completion request.
+type Completion struct {
+	Content      string     `json:"content"`
+	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
+	FinishReason string     `json:"finish_reason,omitempty"` // OpenAI vocabulary: "stop", "length", "tool_calls"
+	Usage        *Usage     `json:"usage,omitempty"`         // nil if the backend did not report it
+	Model        string     `json:"model,omitempty"`         // the model that replied, as reported by the backend
+}
+
+// Usage is the token count of a request as reported by the backend.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// Message returns the completion as an assistant message, for appending to the conversation.
+func (c *Completion) Message() ChatMessage {
+	return ChatMessage{Role: "assistant", Content: c.Content, ToolCalls: c.ToolCalls}
+}
+
+// Sampling holds optional sampling parameters. Nil fields are omitted from the request
+// so the server default applies.
+type Sampling struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	Seed             *int64   `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+}
+
+// ResponseFormat constrains the model's output, e.g. to JSON matching a schema.
+type ResponseFormat struct {
+	Type       string      `json:"type"` // "json_object" or "json_schema"
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema names a JSON schema for a "json_schema" response format.
+type JSONSchema struct {
+	Name   string         `json:"name"`
+	Strict bool           `json:"strict,omitempty"`
+	Schema map[string]any `json:"schema"`
+}
+
+// Options holds per-request overrides for a chat completion.
+type Options struct {
+	Model     string // overrides the configured model if non-empty
+	MaxTokens int    // overrides the default if > 0; 0 means omit max_tokens from the request
+	Sampling  Sampling
+	// ResponseFormat is sent only if the backend supports it (Config.ResponseFormat);
+	// callers must not rely on it being enforced.
+	ResponseFormat *ResponseFormat
+	Tools          []Tool
+	// ProviderOptions are backend-specific model options passed through unchanged by
+	// providers that accept them, such as Ollama's num_ctx; others ignore them.
+	ProviderOptions map[string]any
 }
 
 // ChatCompletionRequest is the request body for the OpenAI-compatible chat API.
 type ChatCompletionRequest struct {
-	Model       string        `json:"model"`
-	Messages    []ChatMessage `json:"messages"`
-	Stream      bool          `json:"stream"`
-	MaxTokens   int           `json:"max_tokens,omitempty"`
-	Temperature float64       `json:"temperature,omitempty"`
+	Model          string          `json:"model"`
+	Messages       []ChatMessage   `json:"messages"`
+	Stream         bool            `json:"stream"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	Tools          []Tool          `json:"tools,omitempty"`
+	StreamOptions  *StreamOptions  `json:"stream_options,omitempty"`
+	Sampling
+}
+
+// StreamOptions configures a streaming response.
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"` // send a final chunk with the request's usage
 }
 
 // ChatCompletionResponse is the non-streaming response.
 type ChatCompletionResponse struct {
 	ID      string   `json:"id"`
+	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage"`
 }
 
 // Choice represents a single completion choice.
 type Choice struct {
 	Index        int         `json:"index"`
 	Message      ChatMessage `json:"message"`
-	Delta        ChatMessage `json:"delta"`
+	Delta        Delta       `json:"delta"`
 	FinishReason *string     `json:"finish_reason"`
 }
 
+// Delta is the incremental part of a message in a streaming chunk.
+type Delta struct {
+	Content   string          `json:"content"`
+	ToolCalls []ToolCallDelta `json:"tool_calls"`
+}
+
+// ToolCallDelta is a fragment of a tool call; fragments with the same Index belong together.
+type ToolCallDelta struct {
+	Index    int          `json:"index"`
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
 // StreamChunk represents a single SSE chunk in a streaming response.
+// With include_usage the last chunk has no choices and carries the usage.
 type StreamChunk struct {
 	ID      string   `json:"id"`
+	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage"`
 }
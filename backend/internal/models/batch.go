@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Batch groups repeated runs of one scenario that differ only by seed.
+type Batch struct {
+	ID            string       `json:"id"`
+	Description   string       `json:"description"`
+	Runs          int          `json:"runs"`
+	BaseSeed      int64        `json:"base_seed"`
+	SimulationIDs []string     `json:"simulation_ids"`
+	Status        string       `json:"status"` // "running", "completed", "failed"
+	Completed     int          `json:"completed"`
+	Failed        int          `json:"failed"`
+	Report        *BatchReport `json:"report,omitempty"`
+	Usage         TokenUsage   `json:"usage"` // of the report; each run records its own
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// BatchReport aggregates the final results of all completed runs in a batch.
+type BatchReport struct {
+	Outcomes      []OutcomeGroup `json:"outcomes"`
+	TurningPoints []string       `json:"turning_points"`
+	Outliers      []Outlier      `json:"outliers"`
+	Summary       string         `json:"summary"`
+}
+
+// OutcomeGroup is one distinct outcome and the runs that reached it.
+type OutcomeGroup struct {
+	Label         string   `json:"label"`
+	Count         int      `json:"count"`
+	SimulationIDs []string `json:"simulation_ids"`
+}
+
+// Outlier is a run whose course or outcome differs markedly from the rest.
+type Outlier struct {
+	SimulationID string `json:"simulation_id"`
+	Reason       string `json:"reason"`
+}
+
+type CreateBatchRequest struct {
+	Simulation CreateSimulationRequest `json:"simulation"`
+	Runs       int                     `json:"runs"`
+	BaseSeed   *int64                  `json:"base_seed,omitempty"` // random if omitted
+}
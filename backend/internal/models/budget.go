@@ -0,0 +1,76 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// StatusBudgetExceeded is the status of a simulation stopped because it reached its budget.
+const StatusBudgetExceeded = "budget_exceeded"
+
+// ModelPrice is the price of a model's tokens, in currency units per million tokens.
+type ModelPrice struct {
+	Model  string  `json:"model"` // also applies to model names it is a prefix of, e.g. dated versions
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Cost returns the price of the given token counts.
+func (p ModelPrice) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+}
+
+// FindPrice returns the price for model: an exact match, or else the longest price
+// whose model name is a prefix of it.
+func FindPrice(prices []ModelPrice, model string) (ModelPrice, bool) {
+	var best ModelPrice
+	found := false
+	for _, p := range prices {
+		if p.Model == model {
+			return p, true
+		}
+		if strings.HasPrefix(model, p.Model) && len(p.Model) > len(best.Model) {
+			best, found = p, true
+		}
+	}
+	return best, found
+}
+
+// Budget limits what a simulation may spend. Exactly one of MaxTokens and MaxCost is set.
+type Budget struct {
+	MaxTokens      int       `json:"max_tokens,omitempty"`
+	MaxCost        float64   `json:"max_cost,omitempty"`        // in the currency of the price table
+	WarnAt         []float64 `json:"warn_at,omitempty"`         // fractions of the budget that trigger a warning
+	SummaryReserve float64   `json:"summary_reserve,omitempty"` // fraction of the budget held back for the final summary
+}
+
+// Default budget settings, applied when a simulation is created.
+var (
+	DefaultBudgetWarnAt         = []float64{0.5, 0.8}
+	DefaultBudgetSummaryReserve = 0.1
+)
+
+// Limit returns the budget in its unit, tokens or currency.
+func (b Budget) Limit() float64 {
+	if b.MaxTokens > 0 {
+		return float64(b.MaxTokens)
+	}
+	return b.MaxCost
+}
+
+// Spent returns usage in the budget's unit.
+func (b Budget) Spent(u TokenUsage) float64 {
+	if b.MaxTokens > 0 {
+		return float64(u.TotalTokens)
+	}
+	return u.Cost
+}
+
+// BudgetWarning records that a simulation's spending reached a fraction of its budget.
+type BudgetWarning struct {
+	Threshold float64   `json:"threshold"` // 1 when the budget stopped the simulation
+	Spent     float64   `json:"spent"`
+	Limit     float64   `json:"limit"`
+	Projected float64   `json:"projected"` // spend projected for the whole run at the current rate
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -0,0 +1,42 @@
+package models
+
+// Comparison is a side-by-side analysis of two or more simulations.
+type Comparison struct {
+	Runs     []ComparisonRun `json:"runs"`
+	Rounds   []AlignedRound  `json:"rounds"`
+	Analysis string          `json:"analysis"`
+	Usage    TokenUsage      `json:"usage"` // of the analysis
+}
+
+// ComparisonRun holds the quantitative profile of one compared simulation.
+type ComparisonRun struct {
+	SimulationID string       `json:"simulation_id"`
+	Label        string       `json:"label"` // "Run 1", "Run 2", ... as referred to in the analysis
+	Status       string       `json:"status"`
+	Model        string       `json:"model,omitempty"`
+	Depth        string       `json:"depth"`
+	Rounds       int          `json:"rounds"` // rounds actually played
+	Steps        int          `json:"steps"`
+	Tokens       int          `json:"tokens"` // used by all of the run's LLM calls
+	Agents       []AgentStats `json:"agents"`
+}
+
+// AgentStats summarises the steps of one agent, matched across runs by name.
+type AgentStats struct {
+	Name       string `json:"name"`
+	Steps      int    `json:"steps"`
+	AvgChars   int    `json:"avg_chars"`
+	AvgTokens  int    `json:"avg_tokens"`
+	TotalChars int    `json:"total_chars"`
+}
+
+// AlignedRound lists what every compared run produced in one round.
+// Steps[i] belongs to Runs[i] and is empty if that run did not reach the round.
+type AlignedRound struct {
+	Round int      `json:"round"`
+	Steps [][]Step `json:"steps"`
+}
+
+type CompareSimulationsRequest struct {
+	SimulationIDs []string `json:"simulation_ids"`
+}
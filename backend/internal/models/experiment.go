@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Experiment is a parameter sweep: one base scenario run once for every combination of axis values.
+type Experiment struct {
+	ID        string                  `json:"id"`
+	Name      string                  `json:"name"`
+	Base      CreateSimulationRequest `json:"base"`
+	Axes      ExperimentAxes          `json:"axes"`
+	Cells     []ExperimentCell        `json:"cells"`
+	Status    string                  `json:"status"` // "running", "completed", "failed"
+	Completed int                     `json:"completed"`
+	Failed    int                     `json:"failed"`
+	CreatedAt time.Time               `json:"created_at"`
+}
+
+// ExperimentAxes lists the values swept by an experiment. An empty axis keeps the base value.
+type ExperimentAxes struct {
+	Depth         []string               `json:"depth,omitempty"`
+	Model         []string               `json:"model,omitempty"`
+	Agents        []AgentsVariant        `json:"agents,omitempty"`
+	Preconditions []PreconditionsVariant `json:"preconditions,omitempty"`
+}
+
+// AgentsVariant is a labelled alternative agent lineup, e.g. with different roles.
+type AgentsVariant struct {
+	Label  string         `json:"label"`
+	Agents []AgentRequest `json:"agents"`
+}
+
+// PreconditionsVariant is a labelled alternative set of preconditions.
+type PreconditionsVariant struct {
+	Label         string `json:"label"`
+	Preconditions string `json:"preconditions"`
+}
+
+// ExperimentCell is one point of the grid and the simulation run for it.
+// Axis fields are empty for axes the experiment does not sweep.
+type ExperimentCell struct {
+	SimulationID  string `json:"simulation_id"`
+	Depth         string `json:"depth,omitempty"`
+	Model         string `json:"model,omitempty"`
+	Agents        string `json:"agents,omitempty"`        // AgentsVariant label
+	Preconditions string `json:"preconditions,omitempty"` // PreconditionsVariant label
+}
+
+// ExperimentResult is one row of an experiment's results matrix.
+type ExperimentResult struct {
+	ExperimentCell
+	Status      string   `json:"status"`
+	Steps       int      `json:"steps"`
+	Score       *float64 `json:"score,omitempty"` // Evaluation.Score, if the simulation has been judged
+	FinalResult string   `json:"final_result,omitempty"`
+}
+
+type CreateExperimentRequest struct {
+	Name string                  `json:"name"`
+	Base CreateSimulationRequest `json:"base"`
+	Axes ExperimentAxes          `json:"axes"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Failure policy actions, taken once all attempts at an agent's turn have failed.
+const (
+	FailureFail     = "fail"     // stop the simulation with status "failed" (default)
+	FailureSkip     = "skip"     // record a StepKindSkipped step and go on with the next agent
+	FailureFallback = "fallback" // make the same attempts with FallbackModel, then fail
+)
+
+// FailurePolicy configures how the engine handles an agent turn whose LLM calls fail.
+type FailurePolicy struct {
+	Retries        int     `json:"retries,omitempty"`         // attempts after the first, per model
+	BackoffSeconds float64 `json:"backoff_seconds,omitempty"` // wait before the first retry; doubles with each further retry
+	OnFailure      string  `json:"on_failure,omitempty"`
+	FallbackModel  string  `json:"fallback_model,omitempty"`
+}
+
+// TurnFailure records one failed attempt at an agent's turn.
+type TurnFailure struct {
+	Attempt    int       `json:"attempt"`
+	Model      string    `json:"model,omitempty"`
+	Error      string    `json:"error"`
+	StatusCode int       `json:"status_code,omitempty"` // HTTP status of the backend's reply, if it sent one
+	Timestamp  time.Time `json:"timestamp"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// Guardrail actions.
+const (
+	GuardPass       = "pass"
+	GuardRedact     = "redact"     // offending text was replaced or cut
+	GuardRegenerate = "regenerate" // the agent was asked for a new reply
+)
+
+// GuardrailConfig configures the content filters an agent's turn passes through before it
+// is saved. Filters run in order: blocklist, PII, length cap, moderation.
+type GuardrailConfig struct {
+	Blocklist   []string `json:"blocklist,omitempty"`    // regular expressions
+	BlockAction string   `json:"block_action,omitempty"` // GuardRedact (default) or GuardRegenerate
+	PII         bool     `json:"pii,omitempty"`          // redact e-mail addresses, phone and card numbers
+	MaxChars    int      `json:"max_chars,omitempty"`    // cut longer replies; 0 means no cap
+	Moderation  bool     `json:"moderation,omitempty"`   // ask the LLM to review the reply; rejected replies are regenerated
+}
+
+// GuardrailOutcome records what one filter did to a step.
+type GuardrailOutcome struct {
+	Filter  string `json:"filter"` // "blocklist", "pii", "length" or "moderation"
+	Action  string `json:"action"`
+	Reason  string `json:"reason,omitempty"`
+	Attempt int    `json:"attempt"` // the reply the filter saw, counting regenerations
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Rubric is a named set of criteria a judge model scores simulations against.
+type Rubric struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Criteria   []Criterion `json:"criteria"`
+	JudgeModel string      `json:"judge_model,omitempty"` // defaults to the server's model
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// Criterion is one scored dimension of a rubric, e.g. "realism" on a 1–5 scale.
+type Criterion struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ScaleMin    int    `json:"scale_min"`
+	ScaleMax    int    `json:"scale_max"`
+}
+
+// Evaluation holds a judge's scores for a simulation against one rubric.
+type Evaluation struct {
+	RubricID   string            `json:"rubric_id"`
+	JudgeModel string            `json:"judge_model,omitempty"`
+	Agents     []AgentEvaluation `json:"agents"`
+	Overall    []CriterionScore  `json:"overall"`
+	Score      float64           `json:"score"` // mean of Overall, normalised to 0–1
+	Usage      TokenUsage        `json:"usage"` // the judge calls
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// AgentEvaluation holds the scores for one agent's steps.
+type AgentEvaluation struct {
+	AgentID   string           `json:"agent_id"`
+	AgentName string           `json:"agent_name"`
+	Scores    []CriterionScore `json:"scores"`
+}
+
+// CriterionScore is the judge's score and rationale for one criterion.
+type CriterionScore struct {
+	Criterion string  `json:"criterion"`
+	Score     float64 `json:"score"`
+	Rationale string  `json:"rationale"`
+}
+
+type CreateRubricRequest struct {
+	Name       string      `json:"name"`
+	Criteria   []Criterion `json:"criteria"`
+	JudgeModel string      `json:"judge_model,omitempty"`
+}
+
+type EvaluateSimulationRequest struct {
+	RubricID string `json:"rubric_id"`
+}
@@ -3,24 +3,106 @@ package models
 import "time"
 
 type Agent struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-	Role string `json:"role"`
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	Role       string          `json:"role"`
+	Sampling   *SamplingParams `json:"sampling,omitempty"`    // per-agent overrides of Simulation.Sampling
+	VoteWeight float64         `json:"vote_weight,omitempty"` // weight under the "weighted" rule; 0 means 1
+	JoinRound  int             `json:"join_round,omitempty"`  // first round the agent takes part in; 0 means 1
+	LeaveRound int             `json:"leave_round,omitempty"` // first round the agent is gone; 0 means never
+	LeftReason string          `json:"left_reason,omitempty"`
+	Added      bool            `json:"added,omitempty"`   // joined while the simulation was running
+	Removed    bool            `json:"removed,omitempty"` // left through a removal or an elimination, not as planned
+}
+
+// PresentIn reports whether the agent takes part in the given round.
+func (a *Agent) PresentIn(round int) bool {
+	return a.JoinRound <= round && (a.LeaveRound == 0 || round < a.LeaveRound)
+}
+
+// PresentAgents returns the agents taking part in the given round.
+func (s *Simulation) PresentAgents(round int) []Agent {
+	present := make([]Agent, 0, len(s.Agents))
+	for _, a := range s.Agents {
+		if a.PresentIn(round) {
+			present = append(present, a)
+		}
+	}
+	return present
+}
+
+// SamplingParams holds the sampling parameters sent with every LLM call.
+// Nil fields are left to the server default.
+type SamplingParams struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	Seed             *int64   `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+}
+
+// Merge returns p with every field set in override replacing the corresponding field.
+func (p SamplingParams) Merge(override *SamplingParams) SamplingParams {
+	if override == nil {
+		return p
+	}
+	if override.Temperature != nil {
+		p.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		p.TopP = override.TopP
+	}
+	if override.Seed != nil {
+		p.Seed = override.Seed
+	}
+	if override.PresencePenalty != nil {
+		p.PresencePenalty = override.PresencePenalty
+	}
+	if override.FrequencyPenalty != nil {
+		p.FrequencyPenalty = override.FrequencyPenalty
+	}
+	if override.Stop != nil {
+		p.Stop = override.Stop
+	}
+	return p
 }
 
 type Simulation struct {
-	ID             string    `json:"id"`
-	Description    string    `json:"description"`
-	Preconditions  string    `json:"preconditions"`
-	Rounds         int       `json:"rounds"`
-	ShowOnlyResult bool      `json:"show_only_result"`
-	Agents         []Agent   `json:"agents"`
-	Language       string    `json:"language"` // "en" or "ru"
-	Depth          string    `json:"depth"`    // "shallow", "medium", "deep"
-	Status         string    `json:"status"`   // "running", "completed", "failed"
-	Steps          []Step    `json:"steps"`
-	FinalResult    string    `json:"final_result,omitempty"`
-	CreatedAt      time.Time `json:"created_at"`
+	ID             string           `json:"id"`
+	Description    string           `json:"description"`
+	Preconditions  string           `json:"preconditions"`
+	Rounds         int              `json:"rounds"`
+	ShowOnlyResult bool             `json:"show_only_result"`
+	Agents         []Agent          `json:"agents"`
+	Language       string           `json:"language"`             // "en" or "ru"
+	Depth          string           `json:"depth"`                // "shallow", "medium", "deep"
+	Model          string           `json:"model,omitempty"`      // overrides the server's default model
+	Structured     bool             `json:"structured,omitempty"` // agents answer with a StructuredTurn JSON object
+	Tools          []string         `json:"tools,omitempty"`      // names of tools agents may call
+	Votes          []VoteConfig     `json:"votes,omitempty"`      // votes scheduled after given rounds
+	Sampling       SamplingParams   `json:"sampling"`
+	Status         string           `json:"status"` // "running", "completed", "failed", "budget_exceeded"
+	Steps          []Step           `json:"steps"`
+	FinalResult    string           `json:"final_result,omitempty"`
+	RubricID       string           `json:"rubric_id,omitempty"`
+	Guardrails     *GuardrailConfig `json:"guardrails,omitempty"`
+	FailurePolicy  *FailurePolicy   `json:"failure_policy,omitempty"`
+	ModelOptions   map[string]any   `json:"model_options,omitempty"` // backend-specific options such as Ollama's num_ctx
+	Record         bool             `json:"record,omitempty"`        // LLM interactions are saved to a cassette
+	ReplayOf       string           `json:"replay_of,omitempty"`     // the recorded simulation this run replays
+	Divergences    []int            `json:"divergences,omitempty"`   // replayed LLM calls whose request differed from the recording
+	Usage          TokenUsage       `json:"usage"`                   // all LLM calls of the run, including the summary, the judge and sub-simulations
+	Budget         *Budget          `json:"budget,omitempty"`
+	BudgetWarnings []BudgetWarning  `json:"budget_warnings,omitempty"`
+	Evaluation     *Evaluation      `json:"evaluation,omitempty"`
+	ParentID       string           `json:"parent_id,omitempty"`       // set on sub-simulations spawned by an agent's turn
+	ParentAgentID  string           `json:"parent_agent_id,omitempty"` // the agent whose turn spawned it
+	ParentRound    int              `json:"parent_round,omitempty"`
+	Level          int              `json:"level,omitempty"` // nesting level; 0 for top-level simulations
+	BatchID        string           `json:"batch_id,omitempty"`
+	ExperimentID   string           `json:"experiment_id,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
 }
 
 // IsInteractive returns true if any agent has a non-empty role.
@@ -45,25 +127,129 @@ func DepthToMaxTokens(depth string) int {
 	}
 }
 
+// Step kinds. Agent turns have an empty kind, or a failure kind if they failed; votes and
+// roster events are visible to every agent.
+const (
+	StepKindVote    = "vote"
+	StepKindRoster  = "roster"  // an agent joined or left; AgentID is that agent
+	StepKindSkipped = "skipped" // the agent's turn failed and was skipped under the failure policy
+	StepKindError   = "error"   // the agent's turn failed and stopped the simulation
+	StepKindBudget  = "budget"  // a budget warning; broadcast only, never part of the transcript
+)
+
 type Step struct {
-	Round     int       `json:"round"`
-	AgentID   string    `json:"agent_id"`
-	AgentName string    `json:"agent_name"`
-	Content   string    `json:"content"`
-	Timestamp time.Time `json:"timestamp"`
+	Round        int                `json:"round"`
+	Kind         string             `json:"kind,omitempty"`
+	AgentID      string             `json:"agent_id"`
+	AgentName    string             `json:"agent_name"`
+	Content      string             `json:"content"`
+	Structured   *StructuredTurn    `json:"structured,omitempty"` // parsed Content in structured mode
+	ToolCalls    []ToolCall         `json:"tool_calls,omitempty"` // tools called during the turn, in order
+	Vote         *Vote              `json:"vote,omitempty"`       // set on StepKindVote steps
+	Children     []string           `json:"children,omitempty"`   // IDs of sub-simulations spawned during the turn
+	Guardrails   []GuardrailOutcome `json:"guardrails,omitempty"`
+	Stale        bool               `json:"stale,omitempty"`        // produced before an earlier step was regenerated or edited
+	Alternatives []StepVersion      `json:"alternatives,omitempty"` // earlier versions of the step, oldest first
+	Edits        []StepEdit         `json:"edits,omitempty"`        // facilitator edits of Content, oldest first
+	Failures     []TurnFailure      `json:"failures,omitempty"`     // failed attempts before this step was produced or given up
+	Model        string             `json:"model,omitempty"`        // set when the fallback model produced the turn
+	Usage        *TokenUsage        `json:"usage,omitempty"`        // LLM calls made for the step, including failed attempts and sub-simulations
+	Budget       *BudgetWarning     `json:"budget,omitempty"`       // set on StepKindBudget steps
+	Timestamp    time.Time          `json:"timestamp"`
+}
+
+// ToolCall records one tool invocation made by an agent during its turn.
+type ToolCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON as sent by the model
+	Result    string `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// StepVersion is an earlier version of a step, kept when the step is regenerated.
+type StepVersion struct {
+	Content    string             `json:"content"`
+	Structured *StructuredTurn    `json:"structured,omitempty"`
+	ToolCalls  []ToolCall         `json:"tool_calls,omitempty"`
+	Guardrails []GuardrailOutcome `json:"guardrails,omitempty"`
+	Usage      *TokenUsage        `json:"usage,omitempty"`
+	Timestamp  time.Time          `json:"timestamp"`
+}
+
+// StepEdit records a facilitator's change to a step's content.
+type StepEdit struct {
+	Original string    `json:"original"`
+	Edited   string    `json:"edited"`
+	Editor   string    `json:"editor"`
+	EditedAt time.Time `json:"edited_at"`
+}
+
+// Version returns the step's current content as a StepVersion.
+func (s Step) Version() StepVersion {
+	return StepVersion{
+		Content:    s.Content,
+		Structured: s.Structured,
+		ToolCalls:  s.ToolCalls,
+		Guardrails: s.Guardrails,
+		Usage:      s.Usage,
+		Timestamp:  s.Timestamp,
+	}
+}
+
+// StructuredTurn is the JSON an agent returns each turn in structured mode.
+type StructuredTurn struct {
+	Analysis        string `json:"analysis"`
+	Decision        string `json:"decision"`
+	Action          string `json:"action"`
+	MessageToOthers string `json:"message_to_others"`
 }
 
 type CreateSimulationRequest struct {
-	Description    string         `json:"description"`
-	Preconditions  string         `json:"preconditions"`
-	Rounds         int            `json:"rounds"`
-	ShowOnlyResult bool           `json:"show_only_result"`
-	Agents         []AgentRequest `json:"agents"`
-	Language       string         `json:"language"`
-	Depth          string         `json:"depth"`
+	Description    string           `json:"description"`
+	Preconditions  string           `json:"preconditions"`
+	Rounds         int              `json:"rounds"`
+	ShowOnlyResult bool             `json:"show_only_result"`
+	Agents         []AgentRequest   `json:"agents"`
+	Language       string           `json:"language"`
+	Depth          string           `json:"depth"`
+	Model          string           `json:"model,omitempty"`
+	Structured     bool             `json:"structured,omitempty"`
+	Tools          []string         `json:"tools,omitempty"`
+	Votes          []VoteConfig     `json:"votes,omitempty"`
+	Sampling       SamplingParams   `json:"sampling"`
+	RubricID       string           `json:"rubric_id,omitempty"` // judge the run against this rubric on completion
+	Record         bool             `json:"record,omitempty"`    // save LLM interactions to a cassette for replay
+	Guardrails     *GuardrailConfig `json:"guardrails,omitempty"`
+	FailurePolicy  *FailurePolicy   `json:"failure_policy,omitempty"`
+	ModelOptions   map[string]any   `json:"model_options,omitempty"` // backend-specific options such as Ollama's num_ctx
+	Budget         *Budget          `json:"budget,omitempty"`
 }
 
 type AgentRequest struct {
-	Name string `json:"name"`
-	Role string `json:"role"`
+	Name       string          `json:"name"`
+	Role       string          `json:"role"`
+	Sampling   *SamplingParams `json:"sampling,omitempty"`
+	VoteWeight float64         `json:"vote_weight,omitempty"`
+	JoinRound  int             `json:"join_round,omitempty"`
+	LeaveRound int             `json:"leave_round,omitempty"`
+}
+
+type RemoveAgentRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Step regeneration modes.
+const (
+	RegenerateStale = "stale" // replace the step in place and mark later steps stale
+	RegenerateRerun = "rerun" // drop later steps and continue the simulation from the new step
+)
+
+type RegenerateStepRequest struct {
+	Mode string `json:"mode"` // defaults to RegenerateStale
+}
+
+type EditStepRequest struct {
+	Content  string `json:"content"`
+	Editor   string `json:"editor"`
+	Continue bool   `json:"continue,omitempty"` // drop later steps and continue the simulation from the edit
 }
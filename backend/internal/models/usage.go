@@ -0,0 +1,21 @@
+package models
+
+// TokenUsage counts the tokens spent on LLM calls.
+type TokenUsage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Estimated        bool    `json:"estimated,omitempty"` // some calls reported no usage and were estimated locally
+	Cost             float64 `json:"cost,omitempty"`      // in the currency of the price table
+	Unpriced         bool    `json:"unpriced,omitempty"`  // some calls used a model without a price and add no cost
+}
+
+// Add adds o to u.
+func (u *TokenUsage) Add(o TokenUsage) {
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.TotalTokens += o.TotalTokens
+	u.Estimated = u.Estimated || o.Estimated
+	u.Cost += o.Cost
+	u.Unpriced = u.Unpriced || o.Unpriced
+}
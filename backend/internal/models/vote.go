@@ -0,0 +1,44 @@
+package models
+
+// Vote rules.
+const (
+	VoteRuleMajority  = "majority"  // an option wins with more than half of the ballots cast
+	VoteRuleUnanimity = "unanimity" // an option wins only if every ballot cast chose it
+	VoteRuleWeighted  = "weighted"  // an option wins with more than half of the cast weight
+)
+
+// VoteAbstain is the choice recorded for an agent that gave no valid ballot.
+const VoteAbstain = "abstain"
+
+// VoteConfig describes a vote the engine runs after all agents have acted in Round.
+type VoteConfig struct {
+	Round    int      `json:"round"`
+	Proposal string   `json:"proposal"`
+	Options  []string `json:"options,omitempty"` // defaults to yes/no
+	Rule     string   `json:"rule,omitempty"`    // defaults to VoteRuleMajority
+	// Eliminate makes the present agents' names the options; the winner leaves the simulation.
+	Eliminate bool `json:"eliminate,omitempty"`
+}
+
+// Vote is the record of a completed vote.
+type Vote struct {
+	VoteConfig
+	Ballots []Ballot      `json:"ballots"`
+	Tally   []OptionTally `json:"tally"`
+	Outcome string        `json:"outcome"` // the winning option, or "" if no option won under the rule
+}
+
+// Ballot is one agent's vote.
+type Ballot struct {
+	AgentID   string  `json:"agent_id"`
+	AgentName string  `json:"agent_name"`
+	Choice    string  `json:"choice"`
+	Weight    float64 `json:"weight"`
+	Rationale string  `json:"rationale"`
+}
+
+// OptionTally is the total cast for one option.
+type OptionTally struct {
+	Option string  `json:"option"`
+	Votes  float64 `json:"votes"` // weighted under VoteRuleWeighted, otherwise a count
+}
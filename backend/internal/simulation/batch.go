@@ -0,0 +1,131 @@
+package simulation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"simarena/internal/llm"
+	"simarena/internal/models"
+)
+
+// BatchCallback is called whenever a batch's progress or report changes.
+type BatchCallback func(batch models.Batch)
+
+// RunBatch executes all runs of a batch asynchronously under the engine's concurrency limit.
+// Once every run has finished, it aggregates their final results into the batch report.
+func (e *Engine) RunBatch(batch *models.Batch, runs []*models.Simulation, onProgress BatchCallback) {
+	go e.runBatch(batch, runs, onProgress)
+}
+
+func (e *Engine) runBatch(batch *models.Batch, runs []*models.Simulation, onProgress BatchCallback) {
+	e.runAll(runs, func(sim *models.Simulation) {
+		if sim.Status == "completed" {
+			batch.Completed++
+		} else {
+			batch.Failed++
+		}
+		e.saveBatch(batch, onProgress)
+	})
+
+	var completed []*models.Simulation
+	for _, sim := range runs {
+		if sim.Status == "completed" {
+			completed = append(completed, sim)
+		}
+	}
+	if len(completed) == 0 {
+		batch.Status = "failed"
+		e.saveBatch(batch, onProgress)
+		return
+	}
+
+	report, err := e.batchReport(context.Background(), completed, &batch.Usage)
+	if err != nil {
+		log.Printf("ERROR: batch %s report failed: %v", batch.ID, err)
+		report = &models.BatchReport{Summary: "Report generation failed: " + err.Error()}
+	}
+	batch.Report = report
+	batch.Status = "completed"
+	e.saveBatch(batch, onProgress)
+}
+
+func (e *Engine) saveBatch(batch *models.Batch, onProgress BatchCallback) {
+	if err := e.store.UpdateBatch(*batch); err != nil {
+		log.Printf("ERROR: failed to update batch: %v", err)
+	}
+	if onProgress != nil {
+		onProgress(*batch)
+	}
+}
+
+// batchReport asks the LLM to aggregate the final results of completed runs, with the
+// model the runs share, and adds the call's tokens to usage. If the reply is not valid
+// JSON, the raw text is kept as the report summary.
+func (e *Engine) batchReport(ctx context.Context, runs []*models.Simulation, usage *models.TokenUsage) (*models.BatchReport, error) {
+	messages := BuildBatchReportMessages(runs)
+	opts := llm.Options{
+		Model:           runs[0].Model,
+		ProviderOptions: runs[0].ModelOptions,
+	}
+	completion, err := e.provider.ChatCompletion(ctx, messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	usage.Add(e.callUsage(opts.Model, messages, completion))
+	content := completion.Content
+
+	var parsed struct {
+		Outcomes []struct {
+			Label string `json:"label"`
+			Runs  []int  `json:"runs"`
+		} `json:"outcomes"`
+		TurningPoints []string `json:"turning_points"`
+		Outliers      []struct {
+			Run    int    `json:"run"`
+			Reason string `json:"reason"`
+		} `json:"outliers"`
+		Summary string `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(extractJSONObject(content)), &parsed); err != nil {
+		log.Printf("WARN: batch report is not valid JSON, keeping raw text: %v", err)
+		return &models.BatchReport{Summary: content}, nil
+	}
+
+	runID := func(n int) (string, error) {
+		if n < 1 || n > len(runs) {
+			return "", fmt.Errorf("report references unknown run %d", n)
+		}
+		return runs[n-1].ID, nil
+	}
+
+	report := &models.BatchReport{
+		Outcomes:      []models.OutcomeGroup{},
+		TurningPoints: parsed.TurningPoints,
+		Outliers:      []models.Outlier{},
+		Summary:       parsed.Summary,
+	}
+	for _, o := range parsed.Outcomes {
+		group := models.OutcomeGroup{Label: o.Label, SimulationIDs: []string{}}
+		for _, n := range o.Runs {
+			id, err := runID(n)
+			if err != nil {
+				log.Printf("WARN: %v", err)
+				continue
+			}
+			group.SimulationIDs = append(group.SimulationIDs, id)
+		}
+		group.Count = len(group.SimulationIDs)
+		report.Outcomes = append(report.Outcomes, group)
+	}
+	for _, o := range parsed.Outliers {
+		id, err := runID(o.Run)
+		if err != nil {
+			log.Printf("WARN: %v", err)
+			continue
+		}
+		report.Outliers = append(report.Outliers, models.Outlier{SimulationID: id, Reason: o.Reason})
+	}
+	return report, nil
+}
@@ -0,0 +1,134 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"simarena/internal/llm"
+	"simarena/internal/models"
+)
+
+// ErrBudgetExhausted is returned for requests that need another agent turn when the
+// simulation's budget does not allow one.
+var ErrBudgetExhausted = errors.New("simulation budget is exhausted")
+
+// budgetTurns returns the number of agent turns taken so far and planned for the whole run.
+func budgetTurns(sim *models.Simulation) (done, total int) {
+	for round := 1; round <= sim.Rounds; round++ {
+		total += len(sim.PresentAgents(round))
+	}
+	for _, step := range sim.Steps {
+		switch step.Kind {
+		case "", models.StepKindSkipped, models.StepKindError:
+			done++
+		}
+	}
+	return done, max(total, done)
+}
+
+// projectSpend returns the spend projected for the whole run at the average cost per turn so far.
+func projectSpend(sim *models.Simulation) float64 {
+	spent := sim.Budget.Spent(sim.Usage)
+	done, total := budgetTurns(sim)
+	if done == 0 {
+		return spent
+	}
+	return spent / float64(done) * float64(total)
+}
+
+// budgetExhausted reports whether another agent turn, at the average cost per turn so
+// far, would eat into the part of the budget held back for the summary.
+func budgetExhausted(sim *models.Simulation) bool {
+	b := sim.Budget
+	if b == nil {
+		return false
+	}
+	spent := b.Spent(sim.Usage)
+	var next float64
+	if done, _ := budgetTurns(sim); done > 0 {
+		next = spent / float64(done)
+	}
+	return spent+next > b.Limit()*(1-b.SummaryReserve)
+}
+
+// warnBudget announces every warning threshold the simulation's spending has reached
+// that was not announced before.
+func (e *Engine) warnBudget(sim *models.Simulation, round int) {
+	b := sim.Budget
+	if b == nil {
+		return
+	}
+	spent := b.Spent(sim.Usage)
+	for _, threshold := range b.WarnAt {
+		if spent < threshold*b.Limit() || slices.ContainsFunc(sim.BudgetWarnings, func(w models.BudgetWarning) bool {
+			return w.Threshold == threshold
+		}) {
+			continue
+		}
+		e.budgetWarning(sim, round, threshold)
+	}
+}
+
+// budgetWarning records a budget warning on the simulation and broadcasts it as a
+// StepKindBudget step, which is not added to the transcript.
+func (e *Engine) budgetWarning(sim *models.Simulation, round int, threshold float64) {
+	w := models.BudgetWarning{
+		Threshold: threshold,
+		Spent:     roundSpend(sim.Budget.Spent(sim.Usage)),
+		Limit:     sim.Budget.Limit(),
+		Projected: roundSpend(projectSpend(sim)),
+		Timestamp: time.Now(),
+	}
+	sim.BudgetWarnings = append(sim.BudgetWarnings, w)
+	log.Printf("WARN: simulation %s has spent %g of its budget of %g (projected %g)", sim.ID, w.Spent, w.Limit, w.Projected)
+	if e.onStep != nil {
+		e.onStep(sim.ID, models.Step{
+			Round:     round,
+			Kind:      models.StepKindBudget,
+			Content:   budgetWarningText(sim, w),
+			Budget:    &w,
+			Timestamp: w.Timestamp,
+		})
+	}
+}
+
+// roundSpend rounds a spend to six decimals, enough for currency amounts.
+func roundSpend(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
+
+// exceedBudget stops a simulation that reached its budget. The summary is still written
+// if the budget left covers it; the run is not evaluated.
+func (e *Engine) exceedBudget(ctx context.Context, sim *models.Simulation, round int) {
+	log.Printf("WARN: simulation %s stopped in round %d: budget exceeded", sim.ID, round)
+	e.budgetWarning(sim, round, 1)
+	if summaryAffordable(sim) {
+		e.summarize(ctx, sim)
+	} else {
+		sim.FinalResult = budgetSummaryText(sim)
+	}
+	sim.Status = models.StatusBudgetExceeded
+	e.finish(sim)
+}
+
+// summaryAffordable reports whether the budget left covers the summary, estimated from
+// its prompt and the average reply so far, at the average price per token so far.
+func summaryAffordable(sim *models.Simulation) bool {
+	b := sim.Budget
+	tokens := llm.EstimateUsage(BuildSummaryMessages(sim), &llm.Completion{}).PromptTokens
+	if done, _ := budgetTurns(sim); done > 0 {
+		tokens += sim.Usage.CompletionTokens / done
+	}
+	need := float64(tokens)
+	if b.MaxTokens == 0 {
+		need = 0
+		if sim.Usage.TotalTokens > 0 {
+			need = float64(tokens) * sim.Usage.Cost / float64(sim.Usage.TotalTokens)
+		}
+	}
+	return b.Spent(sim.Usage)+need <= b.Limit()
+}
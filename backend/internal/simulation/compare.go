@@ -0,0 +1,113 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"simarena/internal/llm"
+	"simarena/internal/models"
+)
+
+// Compare aligns the given simulations round by round, computes their quantitative
+// differences and asks the LLM to analyse where and why their outcomes diverged.
+func (e *Engine) Compare(ctx context.Context, sims []*models.Simulation) (*models.Comparison, error) {
+	cmp := &models.Comparison{
+		Runs:   make([]models.ComparisonRun, 0, len(sims)),
+		Rounds: alignRounds(sims),
+	}
+	for i, sim := range sims {
+		cmp.Runs = append(cmp.Runs, runStats(sim, fmt.Sprintf("Run %d", i+1)))
+	}
+
+	messages := BuildComparisonMessages(sims)
+	var opts llm.Options
+	analysis, err := e.provider.ChatCompletion(ctx, messages, opts)
+	if err != nil {
+		return nil, fmt.Errorf("comparison analysis: %w", err)
+	}
+	cmp.Analysis = analysis.Content
+	cmp.Usage = e.callUsage(opts.Model, messages, analysis)
+	return cmp, nil
+}
+
+// alignRounds groups each simulation's steps by round, up to the longest run.
+func alignRounds(sims []*models.Simulation) []models.AlignedRound {
+	maxRound := 0
+	for _, sim := range sims {
+		for _, step := range sim.Steps {
+			if step.Round > maxRound {
+				maxRound = step.Round
+			}
+		}
+	}
+
+	rounds := make([]models.AlignedRound, maxRound)
+	for r := range rounds {
+		rounds[r] = models.AlignedRound{Round: r + 1, Steps: make([][]models.Step, len(sims))}
+		for i := range sims {
+			rounds[r].Steps[i] = []models.Step{}
+		}
+	}
+	for i, sim := range sims {
+		for _, step := range sim.Steps {
+			if step.Round < 1 {
+				continue
+			}
+			rounds[step.Round-1].Steps[i] = append(rounds[step.Round-1].Steps[i], step)
+		}
+	}
+	return rounds
+}
+
+// runStats computes the quantitative profile of one simulation. Token counts are the
+// recorded usage, estimated from the text for steps recorded without it.
+func runStats(sim *models.Simulation, label string) models.ComparisonRun {
+	run := models.ComparisonRun{
+		SimulationID: sim.ID,
+		Label:        label,
+		Status:       sim.Status,
+		Model:        sim.Model,
+		Depth:        sim.Depth,
+		Steps:        len(sim.Steps),
+		Tokens:       sim.Usage.TotalTokens,
+		Agents:       []models.AgentStats{},
+	}
+	estimated := 0 // tokens of agent turns without usage, for runs recorded before it was
+
+	byName := make(map[string]int)
+	for _, step := range sim.Steps {
+		if step.Round > run.Rounds {
+			run.Rounds = step.Round
+		}
+		if step.Kind != "" {
+			continue // events are not agent output
+		}
+		tokens := llm.EstimateTokens(step.Content)
+		if step.Usage != nil {
+			tokens = step.Usage.CompletionTokens
+		} else {
+			estimated += tokens
+		}
+
+		idx, ok := byName[step.AgentName]
+		if !ok {
+			idx = len(run.Agents)
+			byName[step.AgentName] = idx
+			run.Agents = append(run.Agents, models.AgentStats{Name: step.AgentName})
+		}
+		stats := &run.Agents[idx]
+		stats.Steps++
+		stats.TotalChars += utf8.RuneCountInString(step.Content)
+		stats.AvgTokens += tokens // summed here, averaged below
+	}
+	if run.Tokens == 0 {
+		run.Tokens = estimated
+	}
+	for i := range run.Agents {
+		stats := &run.Agents[i]
+		stats.AvgChars = stats.TotalChars / stats.Steps
+		stats.AvgTokens /= stats.Steps
+	}
+	return run
+}
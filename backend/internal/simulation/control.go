@@ -0,0 +1,91 @@
+package simulation
+
+import (
+	"errors"
+	"sync"
+
+	"simarena/internal/models"
+)
+
+// ErrNotRunning is returned for requests that need a running simulation.
+var ErrNotRunning = errors.New("simulation is not running")
+
+// ErrTooLate is returned for requests that would only take effect after the last round.
+var ErrTooLate = errors.New("too late in the simulation")
+
+// ErrNotPresent is returned for a departure of an agent that is not taking part in the
+// current round: one who has left, is leaving or has yet to join.
+var ErrNotPresent = errors.New("agent is not taking part in the current round")
+
+// ErrBusy is returned for changes to a simulation the engine is already working on.
+var ErrBusy = errors.New("simulation is busy")
+
+// control holds requests made through the API for a running simulation.
+// The run loop applies them at the next round boundary.
+type control struct {
+	mu     sync.Mutex
+	votes  []models.VoteConfig
+	joins  []models.Agent
+	leaves []leaveRequest
+
+	round       int  // the round being played
+	lastRound   bool // joins would never play
+	votesClosed bool // the last round's votes were taken
+}
+
+// startControl registers a control for a simulation that is about to run.
+func (e *Engine) startControl(simID string) *control {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c := &control{}
+	e.controls[simID] = c
+	return c
+}
+
+// claim registers a control for a simulation the engine is about to work on again, or
+// returns ErrBusy if it is working on it already.
+func (e *Engine) claim(simID string) (*control, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.controls[simID]; ok {
+		return nil, ErrBusy
+	}
+	c := &control{}
+	e.controls[simID] = c
+	return c, nil
+}
+
+func (e *Engine) stopControl(simID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.controls, simID)
+}
+
+// control returns the control of a running simulation, or ErrNotRunning.
+func (e *Engine) control(simID string) (*control, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, ok := e.controls[simID]
+	if !ok {
+		return nil, ErrNotRunning
+	}
+	return c, nil
+}
+
+// enterRound records the round the run loop is playing.
+func (c *control) enterRound(round int, last bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.round, c.lastRound = round, last
+}
+
+// takeVotes removes and returns the votes requested since the last call. After the
+// last round's votes are taken, further votes are refused.
+func (c *control) takeVotes(last bool) []models.VoteConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	votes := c.votes
+	c.votes = nil
+	c.votesClosed = last
+	return votes
+}
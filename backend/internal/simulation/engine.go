@@ -1,8 +1,13 @@
 package simulation
 
 import (
+	"cmp"
 	"context"
+	"hash/fnv"
 	"log"
+	"math/rand/v2"
+	"slices"
+	"sync"
 	"time"
 
 	"simarena/internal/llm"
@@ -15,82 +20,213 @@ type StepCallback func(simID string, step models.Step)
 
 // Engine orchestrates simulation runs.
 type Engine struct {
-	llmClient *llm.Client
-	store     *storage.JSONStore
-	onStep    StepCallback
+	provider llm.Provider
+	lister   llm.ModelLister // nil if the provider cannot list its models
+	store    *storage.JSONStore
+	onStep   StepCallback
+	slots    chan struct{}
+	tools    *ToolRegistry
+
+	mu       sync.Mutex
+	controls map[string]*control // by simulation ID, while running
 }
 
-// NewEngine creates a new simulation engine.
-func NewEngine(client *llm.Client, store *storage.JSONStore, onStep StepCallback) *Engine {
+// NewEngine creates a new simulation engine that runs at most maxConcurrent simulations at once.
+func NewEngine(provider llm.Provider, store *storage.JSONStore, maxConcurrent int, onStep StepCallback) *Engine {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	lister, _ := provider.(llm.ModelLister)
 	return &Engine{
-		llmClient: client,
-		store:     store,
-		onStep:    onStep,
+		provider: llm.WithCassettes(provider),
+		lister:   lister,
+		store:    store,
+		onStep:   onStep,
+		slots:    make(chan struct{}, maxConcurrent),
+		tools:    DefaultTools(),
+		controls: make(map[string]*control),
 	}
 }
 
-// Run executes a simulation asynchronously.
-func (e *Engine) Run(sim *models.Simulation) {
-	go e.run(sim)
+// Capabilities reports the optional features of the engine's LLM provider.
+func (e *Engine) Capabilities() llm.Capabilities {
+	return e.provider.Capabilities()
 }
 
-func (e *Engine) run(sim *models.Simulation) {
-	ctx := context.Background()
-	maxTokens := models.DepthToMaxTokens(sim.Depth)
+// Models lists the models the LLM provider serves. ok is false if the provider cannot list them.
+func (e *Engine) Models(ctx context.Context) (names []string, ok bool, err error) {
+	if e.lister == nil {
+		return nil, false, nil
+	}
+	names, err = e.lister.Models(ctx)
+	return names, true, err
+}
 
-	for round := 1; round <= sim.Rounds; round++ {
-		for _, agent := range sim.Agents {
-			messages := BuildAgentRoundMessages(sim, agent, round)
+// Tools returns the registry of tools simulations can enable; register custom tools on it.
+func (e *Engine) Tools() *ToolRegistry {
+	return e.tools
+}
 
-			content, err := e.llmClient.ChatCompletionStream(ctx, messages, maxTokens, nil)
-			if err != nil {
-				log.Printf("ERROR: simulation %s round %d agent %s failed: %v", sim.ID, round, agent.Name, err)
-				sim.Status = "failed"
-				if updateErr := e.store.Update(*sim); updateErr != nil {
-					log.Printf("ERROR: failed to update simulation status: %v", updateErr)
-				}
-				failStep := models.Step{
-					Round:     round,
-					AgentID:   agent.ID,
-					AgentName: agent.Name,
-					Content:   "Error: " + err.Error(),
-					Timestamp: time.Now(),
-				}
-				if e.onStep != nil {
-					e.onStep(sim.ID, failStep)
-				}
-				return
-			}
+// Run executes a simulation asynchronously, waiting for a free slot if the concurrency
+// limit is reached. The returned channel is closed when the simulation has finished.
+// If sim.Record is set, its LLM interactions are saved to a cassette.
+func (e *Engine) Run(sim *models.Simulation) <-chan struct{} {
+	var cas *llm.Cassette
+	if sim.Record {
+		cas = llm.NewRecorder()
+	}
+	return e.start(sim, cas)
+}
 
-			step := models.Step{
-				Round:     round,
-				AgentID:   agent.ID,
-				AgentName: agent.Name,
-				Content:   content,
-				Timestamp: time.Now(),
-			}
-			sim.Steps = append(sim.Steps, step)
+// Replay runs sim like Run, but serves every LLM call from the recorded interactions
+// instead of the network. Calls whose request differs from the recording are listed
+// in sim.Divergences.
+func (e *Engine) Replay(sim *models.Simulation, interactions []llm.Interaction) <-chan struct{} {
+	return e.start(sim, llm.NewReplayer(interactions))
+}
+
+func (e *Engine) start(sim *models.Simulation, cas *llm.Cassette) <-chan struct{} {
+	return e.spawn(sim, e.startControl(sim.ID), func(c *control) {
+		ctx := context.Background()
+		if cas != nil {
+			ctx = llm.WithCassette(ctx, cas)
+		}
+		e.run(ctx, sim, c)
 
+		switch {
+		case sim.Record:
+			if err := e.store.SaveCassette(sim.ID, cas.Interactions()); err != nil {
+				log.Printf("ERROR: failed to save cassette for simulation %s: %v", sim.ID, err)
+			}
+		case sim.ReplayOf != "":
+			sim.Divergences = cas.Divergent()
 			if err := e.store.Update(*sim); err != nil {
-				log.Printf("ERROR: failed to save step: %v", err)
+				log.Printf("ERROR: failed to update simulation: %v", err)
 			}
+		}
+	})
+}
 
-			if e.onStep != nil {
-				e.onStep(sim.ID, step)
+// spawn calls fn in the background once a slot is free, and unregisters sim's control c
+// when fn returns. The returned channel is closed when fn returns.
+func (e *Engine) spawn(sim *models.Simulation, c *control, fn func(c *control)) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer e.stopControl(sim.ID)
+		e.slots <- struct{}{}
+		defer func() { <-e.slots }()
+		fn(c)
+	}()
+	return done
+}
+
+// detach returns a copy of sim for the engine to work on, so that the caller can still
+// read sim while it runs.
+func detach(sim *models.Simulation) *models.Simulation {
+	c := *sim
+	c.Steps = slices.Clone(sim.Steps)
+	c.Agents = slices.Clone(sim.Agents)
+	c.BudgetWarnings = slices.Clone(sim.BudgetWarnings)
+	return &c
+}
+
+// runAll runs every simulation under the concurrency limit and blocks until all have
+// finished, calling onFinish from the calling goroutine as each one finishes.
+func (e *Engine) runAll(sims []*models.Simulation, onFinish func(sim *models.Simulation)) {
+	finished := make(chan *models.Simulation)
+	for _, sim := range sims {
+		done := e.Run(sim)
+		go func() {
+			<-done
+			finished <- sim
+		}()
+	}
+	for range sims {
+		onFinish(<-finished)
+	}
+}
+
+// run plays the simulation's remaining rounds and finishes it. A simulation with steps
+// resumes in the round of its last step, skipping agents that already took their turn.
+// Sub-simulations queue for rate limits as part of their top-level simulation.
+func (e *Engine) run(ctx context.Context, sim *models.Simulation, c *control) {
+	if sim.ParentID == "" {
+		ctx = llm.WithCaller(ctx, sim.ID)
+	}
+	start := 1
+	if n := len(sim.Steps); n > 0 {
+		start = sim.Steps[n-1].Round
+	}
+	for round := start; round <= sim.Rounds; round++ {
+		c.enterRound(round, round == sim.Rounds)
+		e.announceRoster(sim, round)
+
+		for _, agent := range sim.PresentAgents(round) {
+			if hasStep(sim, round, "", agent.ID) {
+				continue
+			}
+			if budgetExhausted(sim) {
+				e.exceedBudget(ctx, sim, round)
+				return
+			}
+			step, stop := e.policyTurn(ctx, sim, agent, round)
+			if stop {
+				log.Printf("ERROR: simulation %s round %d agent %s failed: %s", sim.ID, round, agent.Name, step.Content)
+				sim.Status = "failed"
+				e.appendStep(sim, step)
+				return
 			}
+			e.appendStep(sim, step)
+			e.warnBudget(sim, round)
 		}
+
+		votes := scheduledVotes(sim, round)
+		for _, vote := range c.takeVotes(round == sim.Rounds) {
+			vote.Round = round
+			votes = append(votes, vote)
+		}
+		for _, vote := range votes {
+			if budgetExhausted(sim) {
+				e.exceedBudget(ctx, sim, round)
+				return
+			}
+			e.runVote(ctx, sim, vote)
+			e.warnBudget(sim, round)
+		}
+
+		c.applyRosterRequests(sim, round)
 	}
 
-	// Generate final summary
+	e.summarize(ctx, sim)
+	if sim.RubricID != "" {
+		e.evaluate(ctx, sim)
+	}
+	sim.Status = "completed"
+	e.finish(sim)
+}
+
+// summarize generates the simulation's final summary.
+func (e *Engine) summarize(ctx context.Context, sim *models.Simulation) {
 	summaryMessages := BuildSummaryMessages(sim)
-	summary, err := e.llmClient.ChatCompletion(ctx, summaryMessages, maxTokens)
+	summaryOpts := llm.Options{
+		Model:           sim.Model,
+		MaxTokens:       models.DepthToMaxTokens(sim.Depth),
+		Sampling:        toLLMSampling(sim.Sampling),
+		ProviderOptions: sim.ModelOptions,
+	}
+	completion, err := e.provider.ChatCompletion(ctx, summaryMessages, summaryOpts)
 	if err != nil {
 		log.Printf("ERROR: simulation %s summary failed: %v", sim.ID, err)
-		summary = "Summary generation failed: " + err.Error()
+		sim.FinalResult = "Summary generation failed: " + err.Error()
+		return
 	}
+	sim.FinalResult = completion.Content
+	sim.Usage.Add(e.callUsage(summaryOpts.Model, summaryMessages, completion))
+}
 
-	sim.FinalResult = summary
-	sim.Status = "completed"
+// finish saves the finished simulation and broadcasts its completion.
+func (e *Engine) finish(sim *models.Simulation) {
 	if err := e.store.Update(*sim); err != nil {
 		log.Printf("ERROR: failed to update simulation: %v", err)
 	}
@@ -99,8 +235,193 @@ func (e *Engine) run(sim *models.Simulation) {
 	if e.onStep != nil {
 		e.onStep(sim.ID, models.Step{
 			Round:     -1, // sentinel: indicates completion
-			Content:   summary,
+			Content:   sim.FinalResult,
 			Timestamp: time.Now(),
 		})
 	}
 }
+
+// hasStep reports whether sim has a step of the given kind by the agent in round.
+func hasStep(sim *models.Simulation, round int, kind, agentID string) bool {
+	for _, step := range sim.Steps {
+		if step.Round == round && step.Kind == kind && step.AgentID == agentID {
+			return true
+		}
+	}
+	return false
+}
+
+// appendStep adds a step to the simulation and its usage to the simulation's, saves it
+// and broadcasts it.
+func (e *Engine) appendStep(sim *models.Simulation, step models.Step) {
+	sim.Steps = append(sim.Steps, step)
+	if step.Usage != nil {
+		sim.Usage.Add(*step.Usage)
+	}
+	e.saveStep(sim, step)
+}
+
+// saveStep saves the simulation after step was added or changed, and broadcasts the step.
+func (e *Engine) saveStep(sim *models.Simulation, step models.Step) {
+	if err := e.store.Update(*sim); err != nil {
+		log.Printf("ERROR: failed to save step: %v", err)
+	}
+
+	if e.onStep != nil {
+		e.onStep(sim.ID, step)
+	}
+}
+
+// agentTurn asks the LLM for one agent's step in the given round. If the simulation has
+// guardrails, the reply is filtered and, when a filter rejects it, regenerated. On error
+// the returned step holds only the usage of the calls made so far.
+func (e *Engine) agentTurn(ctx context.Context, sim *models.Simulation, agent models.Agent, round int) (models.Step, error) {
+	messages := BuildAgentRoundMessages(sim, agent, round)
+	opts := llm.Options{
+		Model:           sim.Model,
+		MaxTokens:       models.DepthToMaxTokens(sim.Depth),
+		Sampling:        toLLMSampling(sim.Sampling.Merge(agent.Sampling)),
+		ProviderOptions: sim.ModelOptions,
+	}
+	if sim.Structured && e.provider.Capabilities().ResponseFormat {
+		opts.ResponseFormat = structuredTurnFormat
+	}
+
+	step := models.Step{
+		Round:     round,
+		AgentID:   agent.ID,
+		AgentName: agent.Name,
+	}
+	tc := ToolContext{
+		Ctx:    ctx,
+		Sim:    sim,
+		Agent:  agent,
+		Round:  round,
+		Rand:   toolRand(sim, agent, round),
+		engine: e,
+		step:   &step,
+	}
+	for attempt := 1; ; attempt++ {
+		var err error
+		messages, err = e.reply(ctx, messages, opts, tc, &step)
+		if err != nil {
+			return models.Step{Usage: step.Usage}, err
+		}
+		if sim.Guardrails == nil {
+			break
+		}
+		filter, reason := e.guardStep(ctx, sim, &step, attempt)
+		if filter == "" {
+			break
+		}
+		if attempt == maxGuardAttempts {
+			withhold(sim, &step, filter, attempt)
+			break
+		}
+		messages = append(messages, BuildGuardrailRetryMessage(sim, cmp.Or(reason, filter)))
+	}
+	step.Timestamp = time.Now()
+	return step, nil
+}
+
+// reply gets the agent's reply into step. In structured mode the agent is re-prompted with
+// the validation error until it returns a valid StructuredTurn; if it never does, the last
+// raw reply is kept without parsed fields. It returns the conversation so far.
+func (e *Engine) reply(ctx context.Context, messages []llm.ChatMessage, opts llm.Options, tc ToolContext, step *models.Step) ([]llm.ChatMessage, error) {
+	sim := tc.Sim
+	step.Structured = nil
+	for attempt := 1; ; attempt++ {
+		var content string
+		var err error
+		content, messages, err = e.converse(ctx, messages, opts, tc, step)
+		if err != nil {
+			return messages, err
+		}
+		step.Content = content
+		if !sim.Structured {
+			return messages, nil
+		}
+
+		turn, err := parseStructuredTurn(content)
+		if err == nil {
+			step.Structured = turn
+			return messages, nil
+		}
+		if attempt == maxStructuredAttempts {
+			log.Printf("WARN: simulation %s round %d agent %s gave no valid structured turn: %v", sim.ID, tc.Round, tc.Agent.Name, err)
+			return messages, nil
+		}
+		messages = append(messages, BuildRetryMessage(sim, err.Error(), true))
+	}
+}
+
+// converse sends messages and, while the model keeps calling the simulation's tools, runs
+// the calls, records them on step and sends their results back. It returns the model's
+// final text and the conversation including the final assistant message.
+func (e *Engine) converse(ctx context.Context, messages []llm.ChatMessage, opts llm.Options, tc ToolContext, step *models.Step) (string, []llm.ChatMessage, error) {
+	opts.Tools = e.tools.Definitions(tc.Sim.Tools)
+	for {
+		if len(step.ToolCalls) >= maxToolCallsPerTurn {
+			opts.Tools = nil // force a final answer
+		}
+		completion, err := e.provider.ChatCompletionStream(ctx, messages, opts, nil)
+		if err != nil {
+			return "", messages, err
+		}
+		addStepUsage(step, e.callUsage(opts.Model, messages, completion))
+		messages = append(messages, completion.Message())
+		if len(completion.ToolCalls) == 0 || len(opts.Tools) == 0 {
+			return completion.Content, messages, nil
+		}
+
+		for _, call := range completion.ToolCalls {
+			record := e.tools.invoke(tc, call)
+			step.ToolCalls = append(step.ToolCalls, record)
+			result := record.Result
+			if record.Error != "" {
+				result = "Error: " + record.Error
+			}
+			messages = append(messages, llm.ChatMessage{Role: "tool", Content: result, ToolCallID: call.ID})
+		}
+	}
+}
+
+// toolRand returns the random source for an agent's tool calls in one round. With a fixed
+// simulation seed, rerunning the simulation rolls the same dice.
+func toolRand(sim *models.Simulation, agent models.Agent, round int) *rand.Rand {
+	if sim.Sampling.Seed == nil {
+		return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	h := fnv.New64a()
+	h.Write([]byte(agent.Name))
+	return rand.New(rand.NewPCG(uint64(*sim.Sampling.Seed), h.Sum64()^uint64(round)))
+}
+
+// evaluate scores a finished simulation against its attached rubric. Failures are logged
+// and leave the simulation unscored rather than failing it.
+func (e *Engine) evaluate(ctx context.Context, sim *models.Simulation) {
+	rubric, err := e.store.GetRubric(sim.RubricID)
+	if err != nil {
+		log.Printf("ERROR: simulation %s rubric: %v", sim.ID, err)
+		return
+	}
+	eval, err := e.Evaluate(ctx, sim, rubric)
+	if err != nil {
+		log.Printf("ERROR: simulation %s evaluation failed: %v", sim.ID, err)
+		return
+	}
+	sim.Evaluation = eval
+	sim.Usage.Add(eval.Usage)
+}
+
+// toLLMSampling converts stored sampling parameters into their request form.
+func toLLMSampling(p models.SamplingParams) llm.Sampling {
+	return llm.Sampling{
+		Temperature:      p.Temperature,
+		TopP:             p.TopP,
+		Seed:             p.Seed,
+		PresencePenalty:  p.PresencePenalty,
+		FrequencyPenalty: p.FrequencyPenalty,
+		Stop:             p.Stop,
+	}
+}
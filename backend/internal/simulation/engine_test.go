@@ -0,0 +1,313 @@
+package simulation
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"simarena/internal/llm"
+	"simarena/internal/models"
+	"simarena/internal/storage"
+)
+
+// mockEngine returns an engine on a mock provider following script, with sim stored.
+func mockEngine(t *testing.T, script llm.MockScript, sim models.Simulation) (*Engine, *storage.JSONStore) {
+	t.Helper()
+	store, err := storage.NewJSONStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock, err := llm.NewMock(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(sim); err != nil {
+		t.Fatal(err)
+	}
+	return NewEngine(mock, store, 1, nil), store
+}
+
+// runMock runs sim to completion on a mock provider following script and returns the
+// stored result.
+func runMock(t *testing.T, script llm.MockScript, sim models.Simulation) models.Simulation {
+	t.Helper()
+	e, store := mockEngine(t, script, sim)
+	select {
+	case <-e.Run(&sim):
+	case <-time.After(10 * time.Second):
+		t.Fatal("simulation did not finish")
+	}
+	got, err := store.Get(sim.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *got
+}
+
+func testSimulation() models.Simulation {
+	return models.Simulation{
+		ID:            "sim-1",
+		Description:   "Two shops compete for customers",
+		Preconditions: "Both shops open on the same street.",
+		Rounds:        2,
+		Agents: []models.Agent{
+			{ID: "a", Name: "Alice", Role: "baker"},
+			{ID: "b", Name: "Bob", Role: "grocer"},
+		},
+		Language:  "en",
+		Depth:     "shallow",
+		Status:    "running",
+		Steps:     []models.Step{},
+		CreatedAt: time.Now(),
+	}
+}
+
+func TestRunWithMock(t *testing.T) {
+	script := llm.MockScript{Rules: []llm.MockRule{
+		{Agent: "Alice", Reply: "Alice bakes bread in round {{.Round}}."},
+	}}
+	sim := runMock(t, script, testSimulation())
+
+	if sim.Status != "completed" {
+		t.Fatalf("status = %q", sim.Status)
+	}
+	if len(sim.Steps) != 4 {
+		t.Fatalf("steps = %d, want 4", len(sim.Steps))
+	}
+	for i, step := range sim.Steps {
+		if want := i/2 + 1; step.Round != want {
+			t.Errorf("step %d round = %d, want %d", i, step.Round, want)
+		}
+		if step.Usage == nil || step.Usage.TotalTokens == 0 {
+			t.Errorf("step %d has no usage", i)
+		}
+	}
+	if got := sim.Steps[2].Content; got != "Alice bakes bread in round 2." {
+		t.Errorf("Alice's second turn = %q", got)
+	}
+	if sim.FinalResult == "" {
+		t.Error("no final result")
+	}
+	if sim.Usage.TotalTokens <= sim.Steps[0].Usage.TotalTokens {
+		t.Errorf("simulation usage %d does not add up the steps", sim.Usage.TotalTokens)
+	}
+}
+
+func TestFailurePolicyWithMock(t *testing.T) {
+	script := llm.MockScript{Rules: []llm.MockRule{
+		{Agent: "Alice", Status: 503, Times: 1}, // transient: retried
+		{Agent: "Bob", Status: 400},             // a bad request: not retried
+	}}
+	sim := testSimulation()
+	sim.FailurePolicy = &models.FailurePolicy{Retries: 2, OnFailure: models.FailureSkip}
+	sim = runMock(t, script, sim)
+
+	if sim.Status != "completed" {
+		t.Fatalf("status = %q", sim.Status)
+	}
+	alice := sim.Steps[0]
+	if alice.Kind != "" || len(alice.Failures) != 1 || alice.Failures[0].StatusCode != 503 {
+		t.Errorf("Alice's first turn = kind %q, failures %+v", alice.Kind, alice.Failures)
+	}
+	for _, i := range []int{1, 3} {
+		bob := sim.Steps[i]
+		if bob.Kind != models.StepKindSkipped || len(bob.Failures) != 1 || bob.Failures[0].StatusCode != 400 {
+			t.Errorf("Bob's turn %d = kind %q, failures %+v", i, bob.Kind, bob.Failures)
+		}
+	}
+}
+
+func TestRegenerateUnderPolicyAndBudget(t *testing.T) {
+	script := llm.MockScript{Rules: []llm.MockRule{{Agent: "Bob", Status: 400}}}
+	sim := testSimulation()
+	sim.Rounds = 1
+	sim.FailurePolicy = &models.FailurePolicy{OnFailure: models.FailureSkip}
+	sim.Budget = &models.Budget{MaxTokens: 1_000_000}
+	sim = runMock(t, script, sim)
+
+	e, store := mockEngine(t, llm.MockScript{Rules: []llm.MockRule{{Agent: "Alice", Status: 400}}}, sim)
+	want := sim.Steps[0].Content
+	done, err := e.Regenerate(&sim, 0, models.RegenerateStale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	got, err := store.Get(sim.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "completed" {
+		t.Errorf("status = %q", got.Status)
+	}
+	kept := got.Steps[0]
+	if kept.Kind != "" || kept.Content != want || len(kept.Alternatives) != 0 || len(kept.Failures) != 1 {
+		t.Errorf("failed regeneration changed the step: kind %q, %d alternatives, failures %+v", kept.Kind, len(kept.Alternatives), kept.Failures)
+	}
+	for i, step := range got.Steps {
+		if step.Stale {
+			t.Errorf("step %d marked stale by a failed regeneration", i)
+		}
+	}
+
+	sim = *got
+	sim.Budget.MaxTokens = sim.Usage.TotalTokens
+	if _, err := e.Regenerate(&sim, 0, models.RegenerateStale); err != ErrBudgetExhausted {
+		t.Errorf("err = %v, want ErrBudgetExhausted", err)
+	}
+}
+
+func TestRegenerateRefusedWhileBusy(t *testing.T) {
+	sim := runMock(t, llm.MockScript{}, testSimulation())
+	e, _ := mockEngine(t, llm.MockScript{}, sim)
+
+	e.slots <- struct{}{} // hold the only slot: the regeneration waits for it
+	done, err := e.Regenerate(&sim, 0, models.RegenerateStale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Regenerate(&sim, 1, models.RegenerateStale); err != ErrBusy {
+		t.Errorf("second regeneration: err = %v, want ErrBusy", err)
+	}
+	if _, err := e.Continue(&sim, 1); err != ErrBusy {
+		t.Errorf("continue: err = %v, want ErrBusy", err)
+	}
+	if err := e.EditStep(&sim, 1, "Edited.", "tester"); err != ErrBusy {
+		t.Errorf("edit: err = %v, want ErrBusy", err)
+	}
+	<-e.slots
+	<-done
+}
+
+func TestRewindUndoesDroppedRosterChanges(t *testing.T) {
+	start := time.Now()
+	sim := testSimulation()
+	sim.Agents = append(sim.Agents,
+		models.Agent{ID: "c", Name: "Carol", JoinRound: 2},                                   // planned
+		models.Agent{ID: "d", Name: "Dave", JoinRound: 2, Added: true},                       // joined during round 1
+		models.Agent{ID: "e", Name: "Eve", LeaveRound: 2, LeftReason: "vote", Removed: true}, // eliminated in round 1
+	)
+	sim.Agents[1].LeaveRound = 2 // planned
+	sim.Steps = []models.Step{
+		{Round: 1, AgentID: "a", Content: "Alice's turn.", Timestamp: start},
+		{Round: 1, AgentID: "b", Content: "Bob's turn.", Timestamp: start.Add(time.Second)},
+	}
+	sim.BudgetWarnings = []models.BudgetWarning{
+		{Threshold: 0.5, Timestamp: start},
+		{Threshold: 0.8, Timestamp: start.Add(2 * time.Second)},
+	}
+
+	rewind(&sim, 0)
+	if len(sim.Steps) != 1 {
+		t.Errorf("steps = %d, want 1", len(sim.Steps))
+	}
+	var names []string
+	for _, a := range sim.Agents {
+		names = append(names, a.Name)
+	}
+	if len(names) != 4 || names[3] != "Eve" {
+		t.Fatalf("agents = %v, want Dave's join undone", names)
+	}
+	if eve := sim.Agents[3]; eve.LeaveRound != 0 || eve.Removed {
+		t.Errorf("Eve's elimination not undone: %+v", eve)
+	}
+	if sim.Agents[1].LeaveRound != 2 || sim.Agents[2].JoinRound != 2 {
+		t.Error("planned roster changes undone")
+	}
+	if len(sim.BudgetWarnings) != 1 || sim.BudgetWarnings[0].Threshold != 0.5 {
+		t.Errorf("budget warnings = %+v, want the later one dropped", sim.BudgetWarnings)
+	}
+}
+
+func TestModerationFailsClosed(t *testing.T) {
+	script := llm.MockScript{Rules: []llm.MockRule{
+		{Match: "You moderate a role-play", Status: 503},
+	}}
+	sim := testSimulation()
+	sim.Rounds = 1
+	sim.Guardrails = &models.GuardrailConfig{Moderation: true}
+	sim = runMock(t, script, sim)
+
+	for i, step := range sim.Steps {
+		if step.Content != withheldText(&sim) {
+			t.Errorf("step %d published without moderation: %q", i, step.Content)
+		}
+	}
+}
+
+func TestVoteRationalesAreGuarded(t *testing.T) {
+	script := llm.MockScript{Rules: []llm.MockRule{
+		{Match: "A vote is being held", Reply: `{"choice":"yes","rationale":"The secret plan works."}`},
+	}}
+	sim := testSimulation()
+	sim.Rounds = 1
+	sim.Votes = []models.VoteConfig{{Round: 1, Proposal: "Merge the shops"}}
+	sim.Guardrails = &models.GuardrailConfig{Blocklist: []string{"secret"}, BlockAction: models.GuardRegenerate}
+	sim = runMock(t, script, sim)
+
+	vote := sim.Steps[len(sim.Steps)-1]
+	if vote.Vote == nil {
+		t.Fatalf("last step is not a vote: %+v", vote)
+	}
+	for _, b := range vote.Vote.Ballots {
+		if b.Choice != "yes" || b.Rationale != "" {
+			t.Errorf("ballot %s = %q, %q; want the rejected rationale dropped", b.AgentName, b.Choice, b.Rationale)
+		}
+	}
+	if strings.Contains(vote.Content, "secret") || len(vote.Guardrails) != 2 {
+		t.Errorf("vote step = %q, guardrails %+v", vote.Content, vote.Guardrails)
+	}
+}
+
+func TestEvaluateLaterDropsStaleEvaluation(t *testing.T) {
+	script := llm.MockScript{Rules: []llm.MockRule{
+		{Match: "Score the", Reply: `{"scores":[{"criterion":"realism","score":4}]}`},
+	}}
+	rubric := &models.Rubric{ID: "r", Criteria: []models.Criterion{{Name: "realism", ScaleMin: 1, ScaleMax: 5}}}
+	sim := runMock(t, script, testSimulation())
+
+	for _, edit := range []bool{false, true} {
+		e, store := mockEngine(t, script, sim)
+		e.slots <- struct{}{} // hold the only slot: the evaluation waits for it
+		done := e.EvaluateLater(&sim, rubric)
+		if edit {
+			edited := sim
+			edited.Steps = slices.Clone(sim.Steps)
+			if err := e.EditStep(&edited, 0, "Alice changes her mind.", "tester"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		<-e.slots
+		<-done
+
+		got, err := store.Get(sim.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edit && got.Evaluation != nil {
+			t.Error("evaluation of the old transcript saved onto the edited one")
+		}
+		if !edit && (got.Evaluation == nil || got.Evaluation.Score == 0) {
+			t.Errorf("evaluation = %+v, want it saved", got.Evaluation)
+		}
+	}
+}
+
+func TestIndependentContextHidesPeersFailures(t *testing.T) {
+	sim := testSimulation()
+	sim.Steps = []models.Step{
+		{Round: 1, AgentID: "a", Content: "Alice's turn."},
+		{Round: 1, AgentID: "b", Kind: models.StepKindSkipped, Content: "Bob does not act in this round."},
+		{Round: 1, Kind: models.StepKindVote, Content: "Vote on the proposal."},
+		{Round: 1, AgentID: "c", Kind: models.StepKindRoster, Content: "Carol joins the simulation."},
+	}
+
+	var got []string
+	for _, step := range buildAgentContext(&sim, "a", 2, false) {
+		got = append(got, step.Content)
+	}
+	want := []string{"Alice's turn.", "Vote on the proposal.", "Carol joins the simulation."}
+	if !slices.Equal(got, want) {
+		t.Errorf("independent context = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,27 @@
+package simulation
+
+import (
+	"log"
+
+	"simarena/internal/models"
+)
+
+// RunExperiment executes every cell of an experiment asynchronously under the engine's
+// concurrency limit, keeping the experiment's progress counters up to date.
+func (e *Engine) RunExperiment(exp *models.Experiment, runs []*models.Simulation) {
+	go func() {
+		e.runAll(runs, func(sim *models.Simulation) {
+			if sim.Status == "completed" {
+				exp.Completed++
+			} else {
+				exp.Failed++
+			}
+			if exp.Completed+exp.Failed == len(runs) {
+				exp.Status = "completed"
+			}
+			if err := e.store.UpdateExperiment(*exp); err != nil {
+				log.Printf("ERROR: failed to update experiment: %v", err)
+			}
+		})
+	}()
+}
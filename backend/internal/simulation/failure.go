@@ -0,0 +1,104 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"simarena/internal/llm"
+	"simarena/internal/models"
+)
+
+// policyTurn takes an agent's turn under the simulation's failure policy, recording every
+// failed attempt and its usage on the resulting step. An error that cannot succeed on a
+// retry, such as a bad request, moves on to the fallback model at once. If all attempts
+// fail it returns a StepKindSkipped or StepKindError step; stop reports whether the
+// simulation must end.
+func (e *Engine) policyTurn(ctx context.Context, sim *models.Simulation, agent models.Agent, round int) (step models.Step, stop bool) {
+	var policy models.FailurePolicy
+	if sim.FailurePolicy != nil {
+		policy = *sim.FailurePolicy
+	}
+	candidates := []string{sim.Model}
+	if policy.OnFailure == models.FailureFallback {
+		candidates = append(candidates, policy.FallbackModel)
+	}
+
+	var failures []models.TurnFailure
+	var spent *models.TokenUsage // usage of the failed attempts
+	for _, model := range candidates {
+		turnSim := sim
+		if model != sim.Model {
+			withModel := *sim
+			withModel.Model = model
+			turnSim = &withModel
+		}
+		for attempt := 0; attempt <= policy.Retries; attempt++ {
+			if attempt > 0 && !backoff(ctx, policy.BackoffSeconds, attempt) {
+				break
+			}
+			step, err := e.agentTurn(ctx, turnSim, agent, round)
+			if err == nil {
+				if spent != nil {
+					addStepUsage(&step, *spent)
+				}
+				step.Failures = failures
+				if model != sim.Model {
+					step.Model = model
+				}
+				return step, false
+			}
+			log.Printf("WARN: simulation %s round %d agent %s attempt %d failed: %v", sim.ID, round, agent.Name, len(failures)+1, err)
+			if step.Usage != nil {
+				if spent == nil {
+					spent = &models.TokenUsage{}
+				}
+				spent.Add(*step.Usage)
+			}
+			failure := models.TurnFailure{
+				Attempt:   len(failures) + 1,
+				Model:     model,
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			}
+			var apiErr *llm.APIError
+			if errors.As(err, &apiErr) {
+				failure.StatusCode = apiErr.StatusCode
+			}
+			failures = append(failures, failure)
+			if !llm.IsRetryable(err) {
+				break
+			}
+		}
+	}
+
+	step = models.Step{
+		Round:     round,
+		AgentID:   agent.ID,
+		AgentName: agent.Name,
+		Failures:  failures,
+		Usage:     spent,
+		Timestamp: time.Now(),
+	}
+	if policy.OnFailure == models.FailureSkip {
+		step.Kind = models.StepKindSkipped
+		step.Content = skippedText(sim, agent)
+		return step, false
+	}
+	step.Kind = models.StepKindError
+	step.Content = "Error: " + failures[len(failures)-1].Error
+	return step, true
+}
+
+// backoff waits before retry number attempt, doubling the base delay with each retry.
+// It returns false if ctx is done first.
+func backoff(ctx context.Context, baseSeconds float64, attempt int) bool {
+	delay := time.Duration(baseSeconds * float64(time.Second) * float64(int(1)<<(attempt-1)))
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
@@ -0,0 +1,189 @@
+package simulation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"simarena/internal/llm"
+	"simarena/internal/models"
+)
+
+// maxGuardAttempts bounds how often an agent's reply is regenerated for the guardrails.
+// A reply still rejected after the last attempt is withheld.
+const maxGuardAttempts = 3
+
+const redacted = "[redacted]"
+
+// piiPatterns detect personal data; cards come before phones so long digit runs are labelled as cards.
+var piiPatterns = []struct {
+	label string
+	re    *regexp.Regexp
+}{
+	{"e-mail", regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{"card number", regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)},
+	{"phone number", regexp.MustCompile(`(?:\+\d{1,3}[ -]?)?(?:\(\d{2,4}\)[ -]?|\b\d{3}[ -])\d{3}[ -]?\d{2}[ -]?\d{2}\b`)},
+}
+
+// guardFilter is one text filter of the guardrail chain. check returns the filtered text,
+// the action taken and why; an empty action means the text passed.
+type guardFilter struct {
+	name  string
+	check func(text string) (filtered, action, reason string)
+}
+
+// textFilters builds the text filters enabled by cfg, in chain order. Blocklist patterns
+// are validated when the simulation is created; invalid ones are skipped here.
+func textFilters(cfg *models.GuardrailConfig) []guardFilter {
+	var filters []guardFilter
+	if len(cfg.Blocklist) > 0 {
+		var patterns []*regexp.Regexp
+		for _, p := range cfg.Blocklist {
+			if re, err := regexp.Compile(p); err == nil {
+				patterns = append(patterns, re)
+			}
+		}
+		action := models.GuardRedact
+		if cfg.BlockAction == models.GuardRegenerate {
+			action = models.GuardRegenerate
+		}
+		filters = append(filters, guardFilter{"blocklist", func(text string) (string, string, string) {
+			var matched []string
+			for _, re := range patterns {
+				if re.MatchString(text) {
+					matched = append(matched, re.String())
+					if action == models.GuardRedact {
+						text = re.ReplaceAllString(text, redacted)
+					}
+				}
+			}
+			if len(matched) == 0 {
+				return text, "", ""
+			}
+			return text, action, "matched " + strings.Join(matched, ", ")
+		}})
+	}
+	if cfg.PII {
+		filters = append(filters, guardFilter{"pii", func(text string) (string, string, string) {
+			var found []string
+			for _, p := range piiPatterns {
+				if p.re.MatchString(text) {
+					found = append(found, p.label)
+					text = p.re.ReplaceAllString(text, redacted)
+				}
+			}
+			if len(found) == 0 {
+				return text, "", ""
+			}
+			return text, models.GuardRedact, "found " + strings.Join(found, ", ")
+		}})
+	}
+	if cfg.MaxChars > 0 {
+		filters = append(filters, guardFilter{"length", func(text string) (string, string, string) {
+			runes := []rune(text)
+			if len(runes) <= cfg.MaxChars {
+				return text, "", ""
+			}
+			return string(runes[:cfg.MaxChars]) + "…", models.GuardRedact, fmt.Sprintf("cut to %d characters", cfg.MaxChars)
+		}})
+	}
+	return filters
+}
+
+// stepTexts returns the agent-written texts of a step that the filters apply to.
+func stepTexts(step *models.Step) []*string {
+	texts := []*string{&step.Content}
+	if t := step.Structured; t != nil {
+		texts = append(texts, &t.Analysis, &t.Decision, &t.Action, &t.MessageToOthers)
+	}
+	return texts
+}
+
+// guardStep runs the simulation's guardrail chain over a step, redacting it in place and
+// recording every filter's outcome. If a filter asks for a regeneration the chain stops
+// and guardStep returns that filter's name and reason, which may be empty. A moderation
+// call that fails counts as a rejection. The name is empty if the step passed.
+func (e *Engine) guardStep(ctx context.Context, sim *models.Simulation, step *models.Step, attempt int) (string, string) {
+	for _, f := range textFilters(sim.Guardrails) {
+		outcome := models.GuardrailOutcome{Filter: f.name, Action: models.GuardPass, Attempt: attempt}
+		var reasons []string
+		for _, text := range stepTexts(step) {
+			filtered, action, reason := f.check(*text)
+			if action == "" {
+				continue
+			}
+			if action == models.GuardRegenerate {
+				outcome.Action, outcome.Reason = action, reason
+				step.Guardrails = append(step.Guardrails, outcome)
+				return f.name, reason
+			}
+			*text = filtered
+			outcome.Action = action
+			if !slices.Contains(reasons, reason) {
+				reasons = append(reasons, reason)
+			}
+		}
+		outcome.Reason = strings.Join(reasons, "; ")
+		step.Guardrails = append(step.Guardrails, outcome)
+	}
+
+	if sim.Guardrails.Moderation {
+		outcome := models.GuardrailOutcome{Filter: "moderation", Action: models.GuardPass, Attempt: attempt}
+		allowed, reason, err := e.moderate(ctx, sim, step)
+		switch {
+		case err != nil:
+			// Fail closed: content nobody moderated is not published.
+			log.Printf("WARN: simulation %s moderation failed: %v", sim.ID, err)
+			outcome.Action, outcome.Reason = models.GuardRegenerate, "moderation unavailable: "+err.Error()
+			step.Guardrails = append(step.Guardrails, outcome)
+			return outcome.Filter, ""
+		case !allowed:
+			outcome.Action, outcome.Reason = models.GuardRegenerate, reason
+			step.Guardrails = append(step.Guardrails, outcome)
+			return outcome.Filter, reason
+		}
+		step.Guardrails = append(step.Guardrails, outcome)
+	}
+	return "", ""
+}
+
+// withhold replaces a reply the guardrails kept rejecting.
+func withhold(sim *models.Simulation, step *models.Step, filter string, attempt int) {
+	step.Content = withheldText(sim)
+	step.Structured = nil
+	step.Guardrails = append(step.Guardrails, models.GuardrailOutcome{
+		Filter:  filter,
+		Action:  models.GuardRedact,
+		Reason:  fmt.Sprintf("reply withheld after %d attempts", attempt),
+		Attempt: attempt,
+	})
+}
+
+// moderate asks the LLM whether the step's content is acceptable for the audience,
+// adding the call's tokens to the step's usage.
+func (e *Engine) moderate(ctx context.Context, sim *models.Simulation, step *models.Step) (bool, string, error) {
+	temperature := 0.0
+	opts := llm.Options{
+		Model:           sim.Model,
+		Sampling:        llm.Sampling{Temperature: &temperature},
+		ProviderOptions: sim.ModelOptions,
+	}
+	messages := BuildModerationMessages(sim, step.Content)
+	completion, err := e.provider.ChatCompletion(ctx, messages, opts)
+	if err != nil {
+		return false, "", err
+	}
+	addStepUsage(step, e.callUsage(opts.Model, messages, completion))
+	var verdict struct {
+		Allowed *bool  `json:"allowed"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(extractJSONObject(completion.Content)), &verdict); err != nil || verdict.Allowed == nil {
+		return false, "", fmt.Errorf("unparseable verdict %q", completion.Content)
+	}
+	return *verdict.Allowed, verdict.Reason, nil
+}
@@ -0,0 +1,14 @@
+package simulation
+
+import "strings"
+
+// extractJSONObject returns the outermost {...} span of an LLM reply, dropping any
+// surrounding prose or markdown fences. It returns "" if s contains no object.
+func extractJSONObject(s string) string {
+	start := strings.Index(s, "{")
+	end := strings.LastIndex(s, "}")
+	if start == -1 || end < start {
+		return ""
+	}
+	return s[start : end+1]
+}
@@ -0,0 +1,155 @@
+package simulation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"simarena/internal/llm"
+	"simarena/internal/models"
+)
+
+// EvaluateLater scores a finished simulation against rubric in the background, under the
+// concurrency limit. The evaluation and its usage are saved onto the latest stored copy
+// of the simulation, so that changes made meanwhile are kept; if the transcript changed
+// meanwhile, the evaluation is dropped. The returned channel is closed when the
+// evaluation is saved, dropped or has failed.
+func (e *Engine) EvaluateLater(sim *models.Simulation, rubric *models.Rubric) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.slots <- struct{}{}
+		defer func() { <-e.slots }()
+
+		ctx := llm.WithCaller(context.Background(), sim.ID)
+		eval, err := e.Evaluate(ctx, sim, rubric)
+		if err != nil {
+			log.Printf("ERROR: evaluate simulation %s: %v", sim.ID, err)
+			return
+		}
+		latest, err := e.store.Get(sim.ID)
+		if err != nil {
+			log.Printf("ERROR: simulation %s evaluated but gone: %v", sim.ID, err)
+			return
+		}
+		if latest.Status == "running" || !sameTranscript(sim, latest) {
+			log.Printf("WARN: simulation %s changed while it was evaluated, evaluation dropped", sim.ID)
+			return
+		}
+		latest.RubricID = rubric.ID
+		latest.Evaluation = eval
+		latest.Usage.Add(eval.Usage)
+		if err := e.store.Update(*latest); err != nil {
+			log.Printf("ERROR: failed to save evaluation of simulation %s: %v", sim.ID, err)
+		}
+	}()
+	return done
+}
+
+// sameTranscript reports whether b has the same steps as a, in their same versions.
+func sameTranscript(a, b *models.Simulation) bool {
+	return slices.EqualFunc(a.Steps, b.Steps, func(x, y models.Step) bool {
+		return x.Content == y.Content && x.Timestamp.Equal(y.Timestamp) && len(x.Edits) == len(y.Edits)
+	})
+}
+
+// Evaluate has the judge model score each agent's steps and the whole run against rubric.
+func (e *Engine) Evaluate(ctx context.Context, sim *models.Simulation, rubric *models.Rubric) (*models.Evaluation, error) {
+	eval := &models.Evaluation{
+		RubricID:   rubric.ID,
+		JudgeModel: rubric.JudgeModel,
+		Agents:     make([]models.AgentEvaluation, 0, len(sim.Agents)),
+		CreatedAt:  time.Now(),
+	}
+
+	for _, agent := range sim.Agents {
+		if !hasSteps(sim, agent.ID) {
+			continue // e.g. scheduled to join after the simulation ended
+		}
+		scores, err := e.judge(ctx, BuildJudgeAgentMessages(sim, agent, rubric), rubric, &eval.Usage)
+		if err != nil {
+			return nil, fmt.Errorf("judge agent %s: %w", agent.Name, err)
+		}
+		eval.Agents = append(eval.Agents, models.AgentEvaluation{
+			AgentID:   agent.ID,
+			AgentName: agent.Name,
+			Scores:    scores,
+		})
+	}
+
+	overall, err := e.judge(ctx, BuildJudgeRunMessages(sim, rubric), rubric, &eval.Usage)
+	if err != nil {
+		return nil, fmt.Errorf("judge run: %w", err)
+	}
+	eval.Overall = overall
+	eval.Score = normalisedMean(overall, rubric)
+	return eval, nil
+}
+
+// judge sends one judge prompt and parses the scores, clamped to each criterion's scale.
+// Criteria the judge leaves out are omitted from the result. The call's tokens are added to usage.
+func (e *Engine) judge(ctx context.Context, messages []llm.ChatMessage, rubric *models.Rubric, usage *models.TokenUsage) ([]models.CriterionScore, error) {
+	temperature := 0.0
+	opts := llm.Options{
+		Model:    rubric.JudgeModel,
+		Sampling: llm.Sampling{Temperature: &temperature},
+	}
+	completion, err := e.provider.ChatCompletion(ctx, messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	usage.Add(e.callUsage(opts.Model, messages, completion))
+
+	var parsed struct {
+		Scores []models.CriterionScore `json:"scores"`
+	}
+	if err := json.Unmarshal([]byte(extractJSONObject(completion.Content)), &parsed); err != nil {
+		return nil, fmt.Errorf("parse judge reply: %w", err)
+	}
+
+	byName := make(map[string]models.CriterionScore, len(parsed.Scores))
+	for _, s := range parsed.Scores {
+		byName[s.Criterion] = s
+	}
+	scores := make([]models.CriterionScore, 0, len(rubric.Criteria))
+	for _, c := range rubric.Criteria {
+		s, ok := byName[c.Name]
+		if !ok {
+			log.Printf("WARN: judge returned no score for criterion %q", c.Name)
+			continue
+		}
+		s.Score = max(float64(c.ScaleMin), min(float64(c.ScaleMax), s.Score))
+		scores = append(scores, s)
+	}
+	return scores, nil
+}
+
+// normalisedMean maps every score onto 0–1 using its criterion's scale and averages them.
+func normalisedMean(scores []models.CriterionScore, rubric *models.Rubric) float64 {
+	scales := make(map[string]models.Criterion, len(rubric.Criteria))
+	for _, c := range rubric.Criteria {
+		scales[c.Name] = c
+	}
+	var sum float64
+	for _, s := range scores {
+		c := scales[s.Criterion]
+		sum += (s.Score - float64(c.ScaleMin)) / float64(c.ScaleMax-c.ScaleMin)
+	}
+	if len(scores) == 0 {
+		return 0
+	}
+	return sum / float64(len(scores))
+}
+
+// hasSteps reports whether the agent took at least one turn.
+func hasSteps(sim *models.Simulation, agentID string) bool {
+	for _, step := range sim.Steps {
+		if step.AgentID == agentID && step.Kind == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -14,6 +14,79 @@ func BuildAgentRoundMessages(sim *models.Simulation, agent models.Agent, round i
 	ru := sim.Language == "ru"
 
 	var sys strings.Builder
+	writeAgentContext(&sys, sim, agent, round)
+
+	// User message
+	var user string
+	if interactive {
+		if ru {
+			sys.WriteString(fmt.Sprintf("\nВыполни раунд %d. Учитывай действия других агентов и развивающуюся ситуацию. Опиши свой анализ, решения и действия. Оставайся в роли %s", round, agent.Name))
+			if agent.Role != "" {
+				sys.WriteString(fmt.Sprintf(" (%s)", agent.Role))
+			}
+			sys.WriteString(".\n")
+			user = fmt.Sprintf("Выполни раунд %d. Отвечай на русском языке.", round)
+		} else {
+			user = fmt.Sprintf("Execute round %d. Consider other agents' actions and the evolving situation. Describe your analysis, decisions, and actions. Stay in character as %s", round, agent.Name)
+			if agent.Role != "" {
+				user += fmt.Sprintf(" (%s)", agent.Role)
+			}
+			user += ".\nRespond in English."
+		}
+	} else {
+		if ru {
+			user = fmt.Sprintf("Выполни раунд %d. Опиши свой анализ, решения и действия. Будь подробным и стратегическим. Отвечай на русском языке.", round)
+		} else {
+			user = fmt.Sprintf("Execute round %d. Describe your analysis, decisions, and actions for this round. Be detailed and strategic.\nRespond in English.", round)
+		}
+	}
+
+	if sim.Structured {
+		if ru {
+			user += "\n" + structuredFormatRU
+		} else {
+			user += "\n" + structuredFormatEN
+		}
+	}
+
+	return []llm.ChatMessage{
+		{Role: "system", Content: sys.String()},
+		{Role: "user", Content: user},
+	}
+}
+
+const (
+	structuredFormatEN = `Respond only with a JSON object with the string fields "analysis" (your reading of the situation), "decision" (what you decide), "action" (the concrete action you take this round) and "message_to_others" (what you say to the other participants, or "" if nothing).`
+	structuredFormatRU = `Ответь только JSON-объектом со строковыми полями "analysis" (твой анализ ситуации), "decision" (твоё решение), "action" (конкретное действие в этом раунде) и "message_to_others" (что ты говоришь другим участникам, или "", если ничего). Значения полей пиши на русском языке.`
+)
+
+// BuildRetryMessage constructs the follow-up message asking for a reply that failed
+// validation for the given reason to be given again: in the structured turn format if
+// structured is set, otherwise in the format requested earlier.
+func BuildRetryMessage(sim *models.Simulation, reason string, structured bool) llm.ChatMessage {
+	ru := sim.Language == "ru"
+	var format string
+	switch {
+	case structured && ru:
+		format = structuredFormatRU
+	case structured:
+		format = structuredFormatEN
+	case ru:
+		format = "Ответь ещё раз в запрошенном формате."
+	default:
+		format = "Respond again in the requested format."
+	}
+	if ru {
+		return llm.ChatMessage{Role: "user", Content: fmt.Sprintf("Твой ответ не прошёл проверку: %s. %s", reason, format)}
+	}
+	return llm.ChatMessage{Role: "user", Content: fmt.Sprintf("Your reply failed validation: %s. %s", reason, format)}
+}
+
+// writeAgentContext writes an agent's identity, the scenario, the other participants and the
+// history visible to the agent in the given round.
+func writeAgentContext(sys *strings.Builder, sim *models.Simulation, agent models.Agent, round int) {
+	interactive := sim.IsInteractive()
+	ru := sim.Language == "ru"
 
 	// Agent identity
 	if ru {
@@ -36,15 +109,20 @@ func BuildAgentRoundMessages(sim *models.Simulation, agent models.Agent, round i
 
 	// Other participants (interactive mode only)
 	if interactive {
-		if ru {
-			sys.WriteString("\nДругие участники:\n")
-		} else {
-			sys.WriteString("\nOther participants:\n")
+		var others []models.Agent
+		for _, other := range sim.PresentAgents(round) {
+			if other.ID != agent.ID {
+				others = append(others, other)
+			}
 		}
-		for _, other := range sim.Agents {
-			if other.ID == agent.ID {
-				continue
+		if len(others) > 0 {
+			if ru {
+				sys.WriteString("\nДругие участники:\n")
+			} else {
+				sys.WriteString("\nOther participants:\n")
 			}
+		}
+		for _, other := range others {
 			role := other.Role
 			if role == "" {
 				if ru {
@@ -55,6 +133,28 @@ func BuildAgentRoundMessages(sim *models.Simulation, agent models.Agent, round i
 			}
 			sys.WriteString(fmt.Sprintf("- %s: %s\n", other.Name, role))
 		}
+
+		// Departed participants, whose earlier actions still appear in the history
+		var departed []models.Agent
+		for _, other := range sim.Agents {
+			if other.LeaveRound != 0 && other.LeaveRound <= round {
+				departed = append(departed, other)
+			}
+		}
+		if len(departed) > 0 {
+			if ru {
+				sys.WriteString("\nВыбывшие участники:\n")
+			} else {
+				sys.WriteString("\nFormer participants:\n")
+			}
+			for _, other := range departed {
+				if ru {
+					sys.WriteString(fmt.Sprintf("- %s (выбыл в раунде %d)\n", other.Name, other.LeaveRound))
+				} else {
+					sys.WriteString(fmt.Sprintf("- %s (left in round %d)\n", other.Name, other.LeaveRound))
+				}
+			}
+		}
 	}
 
 	// Round info
@@ -101,36 +201,6 @@ func BuildAgentRoundMessages(sim *models.Simulation, agent models.Agent, round i
 			sys.WriteString("=== End History ===\n")
 		}
 	}
-
-	// User message
-	var user string
-	if interactive {
-		if ru {
-			sys.WriteString(fmt.Sprintf("\nВыполни раунд %d. Учитывай действия других агентов и развивающуюся ситуацию. Опиши свой анализ, решения и действия. Оставайся в роли %s", round, agent.Name))
-			if agent.Role != "" {
-				sys.WriteString(fmt.Sprintf(" (%s)", agent.Role))
-			}
-			sys.WriteString(".\n")
-			user = fmt.Sprintf("Выполни раунд %d. Отвечай на русском языке.", round)
-		} else {
-			user = fmt.Sprintf("Execute round %d. Consider other agents' actions and the evolving situation. Describe your analysis, decisions, and actions. Stay in character as %s", round, agent.Name)
-			if agent.Role != "" {
-				user += fmt.Sprintf(" (%s)", agent.Role)
-			}
-			user += ".\nRespond in English."
-		}
-	} else {
-		if ru {
-			user = fmt.Sprintf("Выполни раунд %d. Опиши свой анализ, решения и действия. Будь подробным и стратегическим. Отвечай на русском языке.", round)
-		} else {
-			user = fmt.Sprintf("Execute round %d. Describe your analysis, decisions, and actions for this round. Be detailed and strategic.\nRespond in English.", round)
-		}
-	}
-
-	return []llm.ChatMessage{
-		{Role: "system", Content: sys.String()},
-		{Role: "user", Content: user},
-	}
 }
 
 // buildAgentContext returns the history steps an agent should see, applying the context window strategy.
@@ -157,8 +227,8 @@ func buildAgentContext(sim *models.Simulation, agentID string, currentRound int,
 		if interactive {
 			result = append(result, step)
 		} else {
-			// Independent mode: only own steps
-			if step.AgentID == agentID {
+			// Independent mode: only own steps, plus votes and roster changes that concern everyone
+			if step.AgentID == agentID || step.Kind == models.StepKindVote || step.Kind == models.StepKindRoster {
 				result = append(result, step)
 			}
 		}
@@ -204,3 +274,347 @@ func BuildSummaryMessages(sim *models.Simulation) []llm.ChatMessage {
 		{Role: "user", Content: user},
 	}
 }
+
+// BuildBatchReportMessages constructs the chat messages that aggregate the final results of
+// repeated runs of one scenario. Runs are numbered from 1 in the order given.
+func BuildBatchReportMessages(runs []*models.Simulation) []llm.ChatMessage {
+	ru := len(runs) > 0 && runs[0].Language == "ru"
+
+	var sys strings.Builder
+	if ru {
+		sys.WriteString("Ты аналитик симуляций. Один и тот же сценарий был запущен несколько раз с разными случайными зёрнами.\n\n")
+	} else {
+		sys.WriteString("You are a simulation analyst. The same scenario was run several times with different random seeds.\n\n")
+	}
+	if len(runs) > 0 {
+		if ru {
+			sys.WriteString(fmt.Sprintf("Сценарий: %s\n", runs[0].Description))
+			sys.WriteString(fmt.Sprintf("Предусловия: %s\n\n", runs[0].Preconditions))
+			sys.WriteString("Итоговые результаты запусков:\n")
+		} else {
+			sys.WriteString(fmt.Sprintf("Scenario: %s\n", runs[0].Description))
+			sys.WriteString(fmt.Sprintf("Preconditions: %s\n\n", runs[0].Preconditions))
+			sys.WriteString("Final results of the runs:\n")
+		}
+	}
+	runHeader := runHeaderEN
+	if ru {
+		runHeader = runHeaderRU
+	}
+	for i, run := range runs {
+		sys.WriteString(fmt.Sprintf(runHeader+"%s\n", i+1, run.FinalResult))
+	}
+
+	var user string
+	if ru {
+		user = "Сгруппируй запуски по итоговому исходу, выдели поворотные моменты, общие для многих запусков, и назови запуски-выбросы. " +
+			`Ответь только JSON-объектом вида {"outcomes":[{"label":"...","runs":[1,2]}],"turning_points":["..."],"outliers":[{"run":3,"reason":"..."}],"summary":"..."}. ` +
+			"Каждый запуск должен входить ровно в один исход. Текстовые поля пиши на русском языке."
+	} else {
+		user = "Group the runs by their final outcome, identify the turning points common to many runs, and name any outlier runs. " +
+			`Respond only with a JSON object of the form {"outcomes":[{"label":"...","runs":[1,2]}],"turning_points":["..."],"outliers":[{"run":3,"reason":"..."}],"summary":"..."}. ` +
+			"Every run must belong to exactly one outcome. Write text fields in English."
+	}
+
+	return []llm.ChatMessage{
+		{Role: "system", Content: sys.String()},
+		{Role: "user", Content: user},
+	}
+}
+
+// comparisonStepChars caps how much of each step is quoted in a comparison prompt,
+// so that comparing long runs stays within the model's context window.
+const comparisonStepChars = 600
+
+// BuildComparisonMessages constructs the chat messages that analyse where and why the given
+// simulations diverged. Runs are numbered from 1 in the order given and aligned round by round.
+func BuildComparisonMessages(sims []*models.Simulation) []llm.ChatMessage {
+	ru := len(sims) > 0 && sims[0].Language == "ru"
+
+	var sys strings.Builder
+	if ru {
+		sys.WriteString("Ты аналитик симуляций. Сравни следующие запуски симуляций.\n\n")
+	} else {
+		sys.WriteString("You are a simulation analyst. Compare the following simulation runs.\n\n")
+	}
+	for i, sim := range sims {
+		if ru {
+			sys.WriteString(fmt.Sprintf("Запуск %d: сценарий: %s; предусловия: %s; глубина: %s", i+1, sim.Description, sim.Preconditions, sim.Depth))
+		} else {
+			sys.WriteString(fmt.Sprintf("Run %d: scenario: %s; preconditions: %s; depth: %s", i+1, sim.Description, sim.Preconditions, sim.Depth))
+		}
+		if sim.Model != "" {
+			sys.WriteString(fmt.Sprintf("; model: %s", sim.Model))
+		}
+		sys.WriteString("\n")
+	}
+
+	maxRound := 0
+	for _, sim := range sims {
+		for _, step := range sim.Steps {
+			if step.Round > maxRound {
+				maxRound = step.Round
+			}
+		}
+	}
+	roundHeader, runHeader, finalHeader := "\n=== Round %d ===\n", "--- Run %d ---\n", "\n=== Final results ===\n"
+	if ru {
+		roundHeader, runHeader, finalHeader = "\n=== Раунд %d ===\n", "--- Запуск %d ---\n", "\n=== Итоговые результаты ===\n"
+	}
+	for round := 1; round <= maxRound; round++ {
+		sys.WriteString(fmt.Sprintf(roundHeader, round))
+		for i, sim := range sims {
+			sys.WriteString(fmt.Sprintf(runHeader, i+1))
+			for _, step := range sim.Steps {
+				if step.Round == round {
+					sys.WriteString(fmt.Sprintf("[%s]: %s\n", step.AgentName, truncate(step.Content, comparisonStepChars)))
+				}
+			}
+		}
+	}
+
+	sys.WriteString(finalHeader)
+	for i, sim := range sims {
+		sys.WriteString(fmt.Sprintf(runHeader, i+1))
+		sys.WriteString(sim.FinalResult + "\n")
+	}
+
+	var user string
+	if ru {
+		user = "Определи, в каком раунде и из-за каких решений запуски начали расходиться, чем отличаются их итоги и чем можно объяснить разницу (модель, глубина, предусловия, случайность). Ссылайся на запуски по номерам. Отвечай на русском языке."
+	} else {
+		user = "Identify the round and the decisions where the runs began to diverge, how their outcomes differ, and what best explains the difference (model, depth, preconditions, chance). Refer to runs by number. Respond in English."
+	}
+
+	return []llm.ChatMessage{
+		{Role: "system", Content: sys.String()},
+		{Role: "user", Content: user},
+	}
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
+
+// BuildJudgeAgentMessages constructs the chat messages asking a judge to score one agent's
+// steps against a rubric.
+func BuildJudgeAgentMessages(sim *models.Simulation, agent models.Agent, rubric *models.Rubric) []llm.ChatMessage {
+	ru := sim.Language == "ru"
+
+	var sys strings.Builder
+	writeJudgeHeader(&sys, sim, rubric, ru)
+	if ru {
+		sys.WriteString(fmt.Sprintf("\nОцениваемый агент: %s\n", agent.Name))
+		if agent.Role != "" {
+			sys.WriteString(fmt.Sprintf("Роль агента: %s\n", agent.Role))
+		}
+		sys.WriteString("\nХоды агента:\n")
+	} else {
+		sys.WriteString(fmt.Sprintf("\nAgent under review: %s\n", agent.Name))
+		if agent.Role != "" {
+			sys.WriteString(fmt.Sprintf("Agent role: %s\n", agent.Role))
+		}
+		sys.WriteString("\nThe agent's steps:\n")
+	}
+	roundHeader := roundHeaderEN
+	if ru {
+		roundHeader = roundHeaderRU
+	}
+	for _, step := range sim.Steps {
+		if step.AgentID == agent.ID && step.Kind == "" {
+			sys.WriteString(fmt.Sprintf(roundHeader+"%s\n", step.Round, step.Content))
+		}
+	}
+
+	var user string
+	if ru {
+		user = fmt.Sprintf("Оцени ходы агента %s по каждому критерию. %s", agent.Name, judgeFormatRU)
+	} else {
+		user = fmt.Sprintf("Score the steps of agent %s on every criterion. %s", agent.Name, judgeFormatEN)
+	}
+
+	return []llm.ChatMessage{
+		{Role: "system", Content: sys.String()},
+		{Role: "user", Content: user},
+	}
+}
+
+// BuildJudgeRunMessages constructs the chat messages asking a judge to score a whole
+// simulation run against a rubric.
+func BuildJudgeRunMessages(sim *models.Simulation, rubric *models.Rubric) []llm.ChatMessage {
+	ru := sim.Language == "ru"
+
+	var sys strings.Builder
+	writeJudgeHeader(&sys, sim, rubric, ru)
+	if ru {
+		sys.WriteString("\nЛог всех раундов:\n")
+	} else {
+		sys.WriteString("\nLog of all rounds:\n")
+	}
+	roundHeader := roundHeaderEN
+	if ru {
+		roundHeader = roundHeaderRU
+	}
+	currentRound := 0
+	for _, step := range sim.Steps {
+		if step.Round != currentRound {
+			currentRound = step.Round
+			sys.WriteString(fmt.Sprintf(roundHeader, step.Round))
+		}
+		sys.WriteString(fmt.Sprintf("[%s]: %s\n", step.AgentName, step.Content))
+	}
+	if sim.FinalResult != "" {
+		if ru {
+			sys.WriteString(fmt.Sprintf("\nИтоговое резюме:\n%s\n", sim.FinalResult))
+		} else {
+			sys.WriteString(fmt.Sprintf("\nFinal summary:\n%s\n", sim.FinalResult))
+		}
+	}
+
+	var user string
+	if ru {
+		user = "Оцени симуляцию в целом по каждому критерию. " + judgeFormatRU
+	} else {
+		user = "Score the simulation as a whole on every criterion. " + judgeFormatEN
+	}
+
+	return []llm.ChatMessage{
+		{Role: "system", Content: sys.String()},
+		{Role: "user", Content: user},
+	}
+}
+
+// Section headers of the judge and batch report prompts.
+const (
+	roundHeaderEN = "\n--- Round %d ---\n"
+	roundHeaderRU = "\n--- Раунд %d ---\n"
+	runHeaderEN   = "\n--- Run %d ---\n"
+	runHeaderRU   = "\n--- Запуск %d ---\n"
+)
+
+const (
+	judgeFormatEN = `Respond only with a JSON object of the form {"scores":[{"criterion":"...","score":3,"rationale":"..."}]}, using the exact criterion names and staying within each scale. Write rationales in English.`
+	judgeFormatRU = `Ответь только JSON-объектом вида {"scores":[{"criterion":"...","score":3,"rationale":"..."}]}, используя точные названия критериев и не выходя за пределы шкал. Обоснования пиши на русском языке.`
+)
+
+// writeJudgeHeader writes the judge's instructions, the scenario and the rubric criteria.
+func writeJudgeHeader(sys *strings.Builder, sim *models.Simulation, rubric *models.Rubric, ru bool) {
+	if ru {
+		sys.WriteString("Ты беспристрастный судья, оценивающий симуляцию по рубрике.\n\n")
+		sys.WriteString(fmt.Sprintf("Сценарий: %s\n", sim.Description))
+		sys.WriteString(fmt.Sprintf("Предусловия: %s\n", sim.Preconditions))
+		sys.WriteString("\nКритерии:\n")
+	} else {
+		sys.WriteString("You are an impartial judge scoring a simulation against a rubric.\n\n")
+		sys.WriteString(fmt.Sprintf("Scenario: %s\n", sim.Description))
+		sys.WriteString(fmt.Sprintf("Preconditions: %s\n", sim.Preconditions))
+		sys.WriteString("\nCriteria:\n")
+	}
+	for _, c := range rubric.Criteria {
+		sys.WriteString(fmt.Sprintf("- %s (%d–%d): %s\n", c.Name, c.ScaleMin, c.ScaleMax, c.Description))
+	}
+}
+
+// BuildVoteMessages constructs the chat messages asking an agent to vote on a proposal.
+func BuildVoteMessages(sim *models.Simulation, agent models.Agent, vote models.VoteConfig) []llm.ChatMessage {
+	ru := sim.Language == "ru"
+
+	var sys strings.Builder
+	writeAgentContext(&sys, sim, agent, vote.Round)
+
+	options := strings.Join(vote.Options, ", ")
+	var user string
+	if ru {
+		user = fmt.Sprintf("Проводится голосование по предложению: %s\nВарианты: %s.\nПроголосуй, оставаясь в роли %s. "+
+			`Ответь только JSON-объектом вида {"choice":"<один из вариантов>","rationale":"..."}. Обоснование пиши на русском языке.`,
+			vote.Proposal, options, agent.Name)
+	} else {
+		user = fmt.Sprintf("A vote is being held on the proposal: %s\nOptions: %s.\nCast your vote in character as %s. "+
+			`Respond only with a JSON object of the form {"choice":"<one of the options>","rationale":"..."}. Write the rationale in English.`,
+			vote.Proposal, options, agent.Name)
+	}
+
+	return []llm.ChatMessage{
+		{Role: "system", Content: sys.String()},
+		{Role: "user", Content: user},
+	}
+}
+
+// BuildGuardrailRetryMessage asks an agent to rewrite a reply the guardrails rejected.
+func BuildGuardrailRetryMessage(sim *models.Simulation, reason string) llm.ChatMessage {
+	if sim.Language == "ru" {
+		return llm.ChatMessage{Role: "user", Content: fmt.Sprintf("Твой ответ отклонён фильтром содержимого: %s. Перепиши его, оставаясь в роли и не нарушая правил.", reason)}
+	}
+	return llm.ChatMessage{Role: "user", Content: fmt.Sprintf("Your reply was rejected by the content filter: %s. Rewrite it, staying in character and within the rules.", reason)}
+}
+
+// BuildModerationMessages asks the LLM to review an agent's reply before it is shown to the audience.
+func BuildModerationMessages(sim *models.Simulation, content string) []llm.ChatMessage {
+	var sys string
+	if sim.Language == "ru" {
+		sys = "Ты модератор ролевой симуляции, которую показывают аудитории семинара. " +
+			"Отклоняй ответы с языком вражды, оскорблениями, сексуальным содержанием, натуралистичным насилием, " +
+			"инструкциями по причинению вреда или персональными данными. Споры, конфликты и резкие мнения в рамках роли допустимы. " +
+			`Ответь только JSON-объектом вида {"allowed":true|false,"reason":"..."}. Причину пиши на русском языке.`
+	} else {
+		sys = "You moderate a role-play simulation shown to a workshop audience. " +
+			"Reject replies containing hate speech, harassment, sexual content, graphic violence, " +
+			"instructions for causing harm or personal data. In-character disagreement, conflict and strong opinions are fine. " +
+			`Respond only with a JSON object of the form {"allowed":true|false,"reason":"..."}.`
+	}
+	return []llm.ChatMessage{
+		{Role: "system", Content: sys},
+		{Role: "user", Content: content},
+	}
+}
+
+// skippedText is the content of a step recording an agent turn skipped after failures.
+func skippedText(sim *models.Simulation, agent models.Agent) string {
+	if sim.Language == "ru" {
+		return fmt.Sprintf("%s не действует в этом раунде.", agent.Name)
+	}
+	return fmt.Sprintf("%s does not act in this round.", agent.Name)
+}
+
+// withheldText replaces an agent reply the guardrails kept rejecting.
+func withheldText(sim *models.Simulation) string {
+	if sim.Language == "ru" {
+		return "[Ответ скрыт фильтром содержимого]"
+	}
+	return "[Reply withheld by the content filter]"
+}
+
+// budgetWarningText describes a budget warning for the facilitator.
+func budgetWarningText(sim *models.Simulation, w models.BudgetWarning) string {
+	unit := "tokens"
+	if sim.Budget.MaxTokens == 0 {
+		unit = "in cost"
+	}
+	if sim.Language == "ru" {
+		unit = "токенов"
+		if sim.Budget.MaxTokens == 0 {
+			unit = "в деньгах"
+		}
+		if w.Threshold >= 1 {
+			return fmt.Sprintf("Бюджет исчерпан: израсходовано %g из %g %s. Симуляция остановлена.", w.Spent, w.Limit, unit)
+		}
+		return fmt.Sprintf("Израсходовано %.0f%% бюджета (%g из %g %s); прогноз на весь прогон: %g.", w.Threshold*100, w.Spent, w.Limit, unit, w.Projected)
+	}
+	if w.Threshold >= 1 {
+		return fmt.Sprintf("Budget reached: spent %g of %g %s. The simulation was stopped.", w.Spent, w.Limit, unit)
+	}
+	return fmt.Sprintf("%.0f%% of the budget spent (%g of %g %s); projected for the whole run: %g.", w.Threshold*100, w.Spent, w.Limit, unit, w.Projected)
+}
+
+// budgetSummaryText replaces the summary when too little budget was left to write it.
+func budgetSummaryText(sim *models.Simulation) string {
+	if sim.Language == "ru" {
+		return "Итог не сформирован: бюджет исчерпан."
+	}
+	return "No summary was written: the budget was exhausted."
+}
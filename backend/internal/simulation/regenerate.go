@@ -0,0 +1,158 @@
+package simulation
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"simarena/internal/llm"
+	"simarena/internal/models"
+)
+
+// Regenerate asks the agent of the step at index seq for a new reply, seeing only the
+// steps before it, and keeps the old reply among the step's alternatives. In
+// models.RegenerateStale mode the step is replaced in place and later steps are marked
+// stale; in models.RegenerateRerun mode later steps are dropped and the simulation
+// continues from the new step. The simulation must have finished and seq must index an
+// agent turn or a skipped one. The new reply is taken under the simulation's failure
+// policy; if it fails, the old step is kept with the failed attempts recorded on it and
+// nothing else changes. Regenerate returns ErrBusy if the engine is working on the
+// simulation already and ErrBudgetExhausted if the budget does not allow another turn,
+// and does nothing then. It works on a copy of sim, which the caller may go on reading.
+// The returned channel is closed when the regeneration has finished.
+func (e *Engine) Regenerate(sim *models.Simulation, seq int, mode string) (<-chan struct{}, error) {
+	c, err := e.claim(sim.ID)
+	if err != nil {
+		return nil, err
+	}
+	if budgetExhausted(sim) {
+		e.stopControl(sim.ID)
+		return nil, ErrBudgetExhausted
+	}
+	old := sim.Steps[seq]
+	var agent models.Agent
+	for _, a := range sim.Agents {
+		if a.ID == old.AgentID {
+			agent = a
+		}
+	}
+
+	prevStatus := sim.Status
+	sim.Status = "running"
+	if err := e.store.Update(*sim); err != nil {
+		log.Printf("ERROR: failed to update simulation: %v", err)
+	}
+
+	sim = detach(sim)
+	return e.spawn(sim, c, func(c *control) {
+		ctx := llm.WithCaller(context.Background(), sim.ID)
+		before := *sim
+		before.Steps = sim.Steps[:seq]
+		step, _ := e.policyTurn(ctx, &before, agent, old.Round)
+		if step.Usage != nil {
+			sim.Usage.Add(*step.Usage)
+		}
+		if step.Kind != "" {
+			log.Printf("ERROR: simulation %s regenerating step %d failed: %s", sim.ID, seq, step.Content)
+			kept := &sim.Steps[seq]
+			kept.Failures = append(kept.Failures, step.Failures...)
+			sim.Status = prevStatus
+			e.finish(sim)
+			return
+		}
+		step.Alternatives = append(old.Alternatives, old.Version())
+
+		if mode == models.RegenerateRerun {
+			rewind(sim, seq)
+			sim.Steps[seq] = step
+			e.saveStep(sim, step)
+			e.run(ctx, sim, c)
+			return
+		}
+
+		sim.Steps[seq] = step
+		markStale(sim, seq)
+		sim.Status = prevStatus
+		e.saveStep(sim, step)
+		if e.onStep != nil {
+			e.onStep(sim.ID, models.Step{
+				Round:     -1, // sentinel: indicates completion
+				Content:   sim.FinalResult,
+				Timestamp: time.Now(),
+			})
+		}
+	}), nil
+}
+
+// Continue drops the steps after index seq and continues the finished simulation from
+// there, on a copy of sim. It returns ErrBusy if the engine is working on the simulation
+// already. The returned channel is closed when the simulation has finished.
+func (e *Engine) Continue(sim *models.Simulation, seq int) (<-chan struct{}, error) {
+	c, err := e.claim(sim.ID)
+	if err != nil {
+		return nil, err
+	}
+	rewind(sim, seq)
+	sim.Status = "running"
+	if err := e.store.Update(*sim); err != nil {
+		log.Printf("ERROR: failed to update simulation: %v", err)
+	}
+	sim = detach(sim)
+	return e.spawn(sim, c, func(c *control) {
+		e.run(context.Background(), sim, c)
+	}), nil
+}
+
+// EditStep replaces the content of the agent turn at index seq with a facilitator's text,
+// recording the original, and marks later steps stale. In structured mode the text is
+// parsed again; if it is no valid structured turn, the parsed fields are dropped.
+// It returns ErrBusy if the engine is working on the simulation.
+func (e *Engine) EditStep(sim *models.Simulation, seq int, content, editor string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.controls[sim.ID]; ok {
+		return ErrBusy
+	}
+	step := &sim.Steps[seq]
+	step.Edits = append(step.Edits, models.StepEdit{
+		Original: step.Content,
+		Edited:   content,
+		Editor:   editor,
+		EditedAt: time.Now(),
+	})
+	step.Content = content
+	step.Stale = false // reviewed by the editor
+	if sim.Structured {
+		step.Structured, _ = parseStructuredTurn(content)
+	}
+	markStale(sim, seq)
+	return e.store.Update(*sim)
+}
+
+// rewind drops the steps after index seq and the results of the finished run, and undoes
+// the joins, departures and budget warnings of the dropped part. The usage is kept: those
+// calls were made.
+func rewind(sim *models.Simulation, seq int) {
+	kept := sim.Steps[seq]
+	sim.Steps = sim.Steps[:seq+1]
+	sim.FinalResult = ""
+	sim.Evaluation = nil
+	sim.Agents = slices.DeleteFunc(sim.Agents, func(a models.Agent) bool {
+		return a.Added && a.JoinRound > kept.Round
+	})
+	for i := range sim.Agents {
+		if a := &sim.Agents[i]; a.Removed && a.LeaveRound > kept.Round {
+			a.LeaveRound, a.LeftReason, a.Removed = 0, "", false
+		}
+	}
+	sim.BudgetWarnings = slices.DeleteFunc(sim.BudgetWarnings, func(w models.BudgetWarning) bool {
+		return w.Timestamp.After(kept.Timestamp)
+	})
+}
+
+func markStale(sim *models.Simulation, seq int) {
+	for i := seq + 1; i < len(sim.Steps); i++ {
+		sim.Steps[i].Stale = true
+	}
+}
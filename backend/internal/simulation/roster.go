@@ -0,0 +1,136 @@
+package simulation
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"simarena/internal/models"
+)
+
+// leaveRequest asks for an agent to be removed from a running simulation.
+type leaveRequest struct {
+	agentID string
+	reason  string
+}
+
+// AddAgent queues an agent to join a running simulation from the next round. It returns
+// ErrTooLate during the last round, when the agent would never play.
+func (e *Engine) AddAgent(simID string, agent models.Agent) error {
+	c, err := e.control(simID)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastRound {
+		return ErrTooLate
+	}
+	c.joins = append(c.joins, agent)
+	return nil
+}
+
+// RemoveAgent queues an agent's departure from a running simulation from the next round.
+// It returns ErrTooLate during the last round, when the departure would have no effect,
+// and ErrNotPresent if the agent is not taking part in the current round or its
+// departure is already queued.
+func (e *Engine) RemoveAgent(simID string, agent models.Agent, reason string) error {
+	c, err := e.control(simID)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastRound {
+		return ErrTooLate
+	}
+	queued := slices.ContainsFunc(c.leaves, func(l leaveRequest) bool { return l.agentID == agent.ID })
+	if !agent.PresentIn(c.round) || agent.LeaveRound != 0 || queued {
+		return ErrNotPresent
+	}
+	c.leaves = append(c.leaves, leaveRequest{agentID: agent.ID, reason: reason})
+	return nil
+}
+
+// applyRosterRequests applies the joins and departures requested during the given round,
+// effective from the next round.
+func (c *control) applyRosterRequests(sim *models.Simulation, round int) {
+	c.mu.Lock()
+	joins, leaves := c.joins, c.leaves
+	c.joins, c.leaves = nil, nil
+	c.mu.Unlock()
+
+	for _, agent := range joins {
+		agent.JoinRound = round + 1
+		agent.Added = true
+		sim.Agents = append(sim.Agents, agent)
+	}
+	for _, req := range leaves {
+		if !scheduleDeparture(sim, req.agentID, round+1, req.reason) {
+			log.Printf("WARN: simulation %s: agent %s is not present in round %d, departure ignored", sim.ID, req.agentID, round)
+		}
+	}
+}
+
+// scheduleDeparture makes a present agent leave from the given round on, as a departure
+// decided during the run. It returns false if no present agent has that ID.
+func scheduleDeparture(sim *models.Simulation, agentID string, round int, reason string) bool {
+	for i := range sim.Agents {
+		a := &sim.Agents[i]
+		if a.ID == agentID && a.PresentIn(round-1) {
+			a.LeaveRound = round
+			a.LeftReason = reason
+			a.Removed = true
+			return true
+		}
+	}
+	return false
+}
+
+// announceRoster records a roster event for every agent joining or leaving in the given round.
+func (e *Engine) announceRoster(sim *models.Simulation, round int) {
+	ru := sim.Language == "ru"
+	for _, agent := range sim.Agents {
+		var content string
+		switch {
+		case agent.JoinRound == round && round > 1:
+			if ru {
+				content = fmt.Sprintf("%s присоединяется к симуляции", agent.Name)
+				if agent.Role != "" {
+					content += fmt.Sprintf(" (роль: %s)", agent.Role)
+				}
+			} else {
+				content = fmt.Sprintf("%s joins the simulation", agent.Name)
+				if agent.Role != "" {
+					content += fmt.Sprintf(" (role: %s)", agent.Role)
+				}
+			}
+		case agent.LeaveRound == round:
+			if ru {
+				content = fmt.Sprintf("%s покидает симуляцию", agent.Name)
+				if agent.LeftReason != "" {
+					content += ": " + agent.LeftReason
+				}
+			} else {
+				content = fmt.Sprintf("%s leaves the simulation", agent.Name)
+				if agent.LeftReason != "" {
+					content += ": " + agent.LeftReason
+				}
+			}
+		default:
+			continue
+		}
+		if hasStep(sim, round, models.StepKindRoster, agent.ID) {
+			continue // announced before the run was resumed
+		}
+		e.appendStep(sim, models.Step{
+			Round:     round,
+			Kind:      models.StepKindRoster,
+			AgentID:   agent.ID,
+			AgentName: agent.Name,
+			Content:   content + ".",
+			Timestamp: time.Now(),
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package simulation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"simarena/internal/llm"
+	"simarena/internal/models"
+)
+
+// maxStructuredAttempts bounds how often an agent is re-prompted for a valid structured turn.
+const maxStructuredAttempts = 3
+
+// structuredTurnFormat is the response_format sent with structured turns when the backend supports it.
+var structuredTurnFormat = &llm.ResponseFormat{
+	Type: "json_schema",
+	JSONSchema: &llm.JSONSchema{
+		Name:   "agent_turn",
+		Strict: true,
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"analysis":          map[string]any{"type": "string"},
+				"decision":          map[string]any{"type": "string"},
+				"action":            map[string]any{"type": "string"},
+				"message_to_others": map[string]any{"type": "string"},
+			},
+			"required":             []string{"analysis", "decision", "action", "message_to_others"},
+			"additionalProperties": false,
+		},
+	},
+}
+
+// parseStructuredTurn extracts and validates a StructuredTurn from an agent reply.
+// The returned error is phrased so it can be shown to the agent when re-prompting.
+func parseStructuredTurn(content string) (*models.StructuredTurn, error) {
+	raw := extractJSONObject(content)
+	if raw == "" {
+		return nil, errors.New("no JSON object found")
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	for _, key := range []string{"analysis", "decision", "action", "message_to_others"} {
+		if _, ok := fields[key]; !ok {
+			return nil, fmt.Errorf("missing field %q", key)
+		}
+	}
+
+	var turn models.StructuredTurn
+	if err := json.Unmarshal([]byte(raw), &turn); err != nil {
+		return nil, fmt.Errorf("fields must be strings: %v", err)
+	}
+	if turn.Decision == "" || turn.Action == "" {
+		return nil, errors.New(`"decision" and "action" must not be empty`)
+	}
+	return &turn, nil
+}
@@ -0,0 +1,179 @@
+package simulation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"simarena/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxNestingLevel bounds how deep sub-simulations can nest.
+	maxNestingLevel = 2
+	holdMeetingName = "hold_meeting"
+)
+
+var holdMeetingTool = Tool{
+	Name: holdMeetingName,
+	Description: "Hold an internal meeting as a separate sub-simulation with its own participants and rounds, " +
+		"e.g. a ministry's internal discussion. Returns the meeting's final summary.",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"topic":   map[string]any{"type": "string", "description": "What the meeting must discuss or decide."},
+			"context": map[string]any{"type": "string", "description": "Facts the participants should know."},
+			"rounds":  map[string]any{"type": "integer", "description": "Number of rounds, 1-5. Defaults to 3."},
+			"participants": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"name": map[string]any{"type": "string"},
+						"role": map[string]any{"type": "string"},
+					},
+					"required": []string{"name", "role"},
+				},
+				"description": "1-5 participants of the meeting.",
+			},
+		},
+		"required": []string{"topic", "participants"},
+	},
+	Call: func(tc ToolContext, raw json.RawMessage) (string, error) {
+		var args struct {
+			Topic        string                `json:"topic"`
+			Context      string                `json:"context"`
+			Rounds       int                   `json:"rounds"`
+			Participants []models.AgentRequest `json:"participants"`
+		}
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %v", err)
+		}
+		if strings.TrimSpace(args.Topic) == "" {
+			return "", errors.New("topic must not be empty")
+		}
+		if len(args.Participants) < 1 || len(args.Participants) > 5 {
+			return "", errors.New("a meeting needs 1-5 participants")
+		}
+		if args.Rounds == 0 {
+			args.Rounds = 3
+		}
+		if args.Rounds < 1 || args.Rounds > 5 {
+			return "", errors.New("rounds must be 1-5")
+		}
+		if tc.Sim.Level >= maxNestingLevel {
+			return "", errors.New("meetings cannot be nested this deep")
+		}
+		return tc.engine.runChild(tc, args.Topic, args.Context, args.Rounds, args.Participants)
+	},
+}
+
+// runChild runs a sub-simulation to completion within the calling turn and returns its
+// final result. The child is stored as a normal simulation linked to its parent and
+// runs in the parent's concurrency slot, recorded on the parent's cassette if any. It
+// inherits the parent's settings and may spend what is left of the parent's budget.
+func (e *Engine) runChild(tc ToolContext, topic, extra string, rounds int, participants []models.AgentRequest) (string, error) {
+	parent := tc.Sim
+	agents := make([]models.Agent, 0, len(participants))
+	for _, p := range participants {
+		if p.Name == "" {
+			p.Name = "Agent"
+		}
+		agents = append(agents, models.Agent{ID: uuid.New().String(), Name: p.Name, Role: p.Role})
+	}
+
+	var preconditions strings.Builder
+	if parent.Language == "ru" {
+		preconditions.WriteString(fmt.Sprintf("Это внутреннее совещание, созванное участником %s в раунде %d более широкой симуляции: %s\n", tc.Agent.Name, tc.Round, parent.Description))
+	} else {
+		preconditions.WriteString(fmt.Sprintf("This is an internal meeting called by %s in round %d of a wider simulation: %s\n", tc.Agent.Name, tc.Round, parent.Description))
+	}
+	if extra != "" {
+		preconditions.WriteString(extra)
+	}
+
+	budget, ok := childBudget(parent, tc.step)
+	if !ok {
+		return "", errors.New("the budget left does not allow a meeting")
+	}
+
+	child := models.Simulation{
+		ID:            uuid.New().String(),
+		Description:   topic,
+		Preconditions: preconditions.String(),
+		Rounds:        rounds,
+		Agents:        agents,
+		Language:      parent.Language,
+		Depth:         parent.Depth,
+		Model:         parent.Model,
+		Sampling:      parent.Sampling,
+		Tools:         childTools(parent),
+		Guardrails:    parent.Guardrails,
+		ModelOptions:  parent.ModelOptions,
+		FailurePolicy: parent.FailurePolicy,
+		Budget:        budget,
+		Status:        "running",
+		Steps:         []models.Step{},
+		ParentID:      parent.ID,
+		ParentAgentID: tc.Agent.ID,
+		ParentRound:   tc.Round,
+		Level:         parent.Level + 1,
+		CreatedAt:     time.Now(),
+	}
+	if err := e.store.Create(child); err != nil {
+		return "", fmt.Errorf("save meeting: %w", err)
+	}
+	tc.step.Children = append(tc.step.Children, child.ID)
+
+	c := e.startControl(child.ID)
+	defer e.stopControl(child.ID)
+	e.run(tc.Ctx, &child, c)
+	addStepUsage(tc.step, child.Usage)
+
+	switch child.Status {
+	case "completed":
+	case models.StatusBudgetExceeded:
+		return "", errors.New("the meeting ran out of budget")
+	default:
+		return "", errors.New("the meeting failed")
+	}
+	return child.FinalResult, nil
+}
+
+// childBudget returns the budget of a meeting called during step: what the parent has
+// left before its summary reserve, counting the step's spending so far. ok is false if
+// nothing is left.
+func childBudget(parent *models.Simulation, step *models.Step) (budget *models.Budget, ok bool) {
+	b := parent.Budget
+	if b == nil {
+		return nil, true
+	}
+	usage := parent.Usage
+	if step.Usage != nil {
+		usage.Add(*step.Usage)
+	}
+	left := b.Limit()*(1-b.SummaryReserve) - b.Spent(usage)
+	budget = &models.Budget{SummaryReserve: b.SummaryReserve}
+	if b.MaxTokens > 0 {
+		budget.MaxTokens = int(left)
+		return budget, budget.MaxTokens > 0
+	}
+	budget.MaxCost = left
+	return budget, left > 0
+}
+
+// childTools returns the parent's tools, minus hold_meeting once the next level is the deepest.
+func childTools(parent *models.Simulation) []string {
+	var tools []string
+	for _, name := range parent.Tools {
+		if name == holdMeetingName && parent.Level+1 >= maxNestingLevel {
+			continue
+		}
+		tools = append(tools, name)
+	}
+	return tools
+}
@@ -0,0 +1,401 @@
+package simulation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"simarena/internal/llm"
+	"simarena/internal/models"
+)
+
+// maxToolCallsPerTurn bounds the tool calls an agent may make before it must answer.
+const maxToolCallsPerTurn = 8
+
+// ToolContext is what a tool can see when an agent calls it.
+type ToolContext struct {
+	Ctx   context.Context
+	Sim   *models.Simulation
+	Agent models.Agent
+	Round int
+	Rand  *rand.Rand
+
+	engine *Engine
+	step   *models.Step // the turn in progress
+}
+
+// Tool is a function agents can call during their turn.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any // JSON schema of the arguments object
+	Call        func(tc ToolContext, args json.RawMessage) (string, error)
+}
+
+// ToolRegistry holds the tools simulations can enable by name.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]Tool
+}
+
+// NewToolRegistry creates an empty tool registry.
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: make(map[string]Tool)}
+}
+
+// DefaultTools returns a registry with the built-in tools registered.
+func DefaultTools() *ToolRegistry {
+	r := NewToolRegistry()
+	r.Register(rollDiceTool)
+	r.Register(worldStateTool)
+	r.Register(lookupPreconditionsTool)
+	r.Register(calculateTool)
+	r.Register(holdMeetingTool)
+	return r
+}
+
+// Register adds a tool, replacing any tool with the same name.
+func (r *ToolRegistry) Register(t Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools[t.Name] = t
+}
+
+// Get returns the tool with the given name.
+func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tools[name]
+	return t, ok
+}
+
+// List returns all registered tools sorted by name.
+func (r *ToolRegistry) List() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tools := make([]Tool, 0, len(r.tools))
+	for _, t := range r.tools {
+		tools = append(tools, t)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// Definitions returns the request definitions of the named tools, skipping unknown names.
+func (r *ToolRegistry) Definitions(names []string) []llm.Tool {
+	defs := make([]llm.Tool, 0, len(names))
+	for _, name := range names {
+		t, ok := r.Get(name)
+		if !ok {
+			continue
+		}
+		defs = append(defs, llm.Tool{
+			Type: "function",
+			Function: llm.FunctionDef{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.Parameters,
+			},
+		})
+	}
+	return defs
+}
+
+// invoke runs one tool call requested by the model and records it.
+// Errors are reported back to the model as the tool result rather than failing the turn.
+func (r *ToolRegistry) invoke(tc ToolContext, call llm.ToolCall) models.ToolCall {
+	record := models.ToolCall{Name: call.Function.Name, Arguments: call.Function.Arguments}
+	t, ok := r.Get(call.Function.Name)
+	if !ok || !enabled(tc.Sim, call.Function.Name) {
+		record.Error = fmt.Sprintf("unknown tool %q", call.Function.Name)
+		return record
+	}
+	args := json.RawMessage(call.Function.Arguments)
+	if len(strings.TrimSpace(call.Function.Arguments)) == 0 {
+		args = json.RawMessage("{}")
+	}
+	result, err := t.Call(tc, args)
+	if err != nil {
+		record.Error = err.Error()
+		return record
+	}
+	record.Result = result
+	return record
+}
+
+func enabled(sim *models.Simulation, name string) bool {
+	for _, n := range sim.Tools {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+var rollDiceTool = Tool{
+	Name:        "roll_dice",
+	Description: "Roll one or more dice and return each result and the total.",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"count": map[string]any{"type": "integer", "description": "Number of dice, 1-20. Defaults to 1."},
+			"sides": map[string]any{"type": "integer", "description": "Sides per die, 2-100. Defaults to 6."},
+		},
+	},
+	Call: func(tc ToolContext, raw json.RawMessage) (string, error) {
+		args := struct {
+			Count int `json:"count"`
+			Sides int `json:"sides"`
+		}{Count: 1, Sides: 6}
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %v", err)
+		}
+		if args.Count < 1 || args.Count > 20 || args.Sides < 2 || args.Sides > 100 {
+			return "", errors.New("count must be 1-20 and sides 2-100")
+		}
+		rolls := make([]string, args.Count)
+		total := 0
+		for i := range rolls {
+			n := tc.Rand.IntN(args.Sides) + 1
+			rolls[i] = strconv.Itoa(n)
+			total += n
+		}
+		return fmt.Sprintf("Rolled %dd%d: %s (total %d)", args.Count, args.Sides, strings.Join(rolls, ", "), total), nil
+	},
+}
+
+var worldStateTool = Tool{
+	Name:        "world_state",
+	Description: "Get the current round, the participants and each participant's most recent action.",
+	Parameters:  map[string]any{"type": "object", "properties": map[string]any{}},
+	Call: func(tc ToolContext, _ json.RawMessage) (string, error) {
+		var b strings.Builder
+		b.WriteString(fmt.Sprintf("Round %d of %d.\n", tc.Round, tc.Sim.Rounds))
+		for _, agent := range tc.Sim.PresentAgents(tc.Round) {
+			b.WriteString(fmt.Sprintf("- %s", agent.Name))
+			if agent.Role != "" {
+				b.WriteString(fmt.Sprintf(" (%s)", agent.Role))
+			}
+			last := lastStep(tc.Sim, agent.ID)
+			if last == nil {
+				b.WriteString(": has not acted yet\n")
+				continue
+			}
+			action := last.Content
+			if last.Structured != nil {
+				action = last.Structured.Action
+			}
+			b.WriteString(fmt.Sprintf(", round %d: %s\n", last.Round, truncate(action, 300)))
+		}
+		return b.String(), nil
+	},
+}
+
+// lastStep returns the most recent turn taken by the given agent, or nil.
+func lastStep(sim *models.Simulation, agentID string) *models.Step {
+	for i := len(sim.Steps) - 1; i >= 0; i-- {
+		if sim.Steps[i].AgentID == agentID && sim.Steps[i].Kind == "" {
+			return &sim.Steps[i]
+		}
+	}
+	return nil
+}
+
+var lookupPreconditionsTool = Tool{
+	Name:        "lookup_preconditions",
+	Description: "Search the scenario description and preconditions for sentences containing all the given keywords.",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{"type": "string", "description": "Keywords to look for."},
+		},
+		"required": []string{"query"},
+	},
+	Call: func(tc ToolContext, raw json.RawMessage) (string, error) {
+		var args struct {
+			Query string `json:"query"`
+		}
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %v", err)
+		}
+		keywords := strings.Fields(strings.ToLower(args.Query))
+		if len(keywords) == 0 {
+			return "", errors.New("query must not be empty")
+		}
+
+		sentences := strings.FieldsFunc(tc.Sim.Description+"\n"+tc.Sim.Preconditions, func(r rune) bool {
+			return r == '.' || r == '\n' || r == '!' || r == '?'
+		})
+		var matches []string
+		for _, sentence := range sentences {
+			lower := strings.ToLower(sentence)
+			all := true
+			for _, k := range keywords {
+				if !strings.Contains(lower, k) {
+					all = false
+					break
+				}
+			}
+			if all {
+				matches = append(matches, strings.TrimSpace(sentence))
+			}
+		}
+		if len(matches) == 0 {
+			return "No matching facts in the scenario.", nil
+		}
+		return strings.Join(matches, "\n"), nil
+	},
+}
+
+var calculateTool = Tool{
+	Name:        "calculate",
+	Description: "Evaluate an arithmetic expression with + - * / and parentheses.",
+	Parameters: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"expression": map[string]any{"type": "string", "description": "For example (120 - 35) * 1.5"},
+		},
+		"required": []string{"expression"},
+	},
+	Call: func(_ ToolContext, raw json.RawMessage) (string, error) {
+		var args struct {
+			Expression string `json:"expression"`
+		}
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %v", err)
+		}
+		v, err := evalArithmetic(args.Expression)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	},
+}
+
+// evalArithmetic evaluates an expression of numbers, + - * /, unary minus and parentheses.
+func evalArithmetic(expr string) (float64, error) {
+	p := &arithParser{src: []rune(expr)}
+	v, err := p.sum()
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.src[p.pos], p.pos+1)
+	}
+	return v, nil
+}
+
+type arithParser struct {
+	src []rune
+	pos int
+}
+
+func (p *arithParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space rune, or 0 at the end of input.
+func (p *arithParser) peek() rune {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *arithParser) sum() (float64, error) {
+	v, err := p.product()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch p.peek() {
+		case '+':
+			p.pos++
+			w, err := p.product()
+			if err != nil {
+				return 0, err
+			}
+			v += w
+		case '-':
+			p.pos++
+			w, err := p.product()
+			if err != nil {
+				return 0, err
+			}
+			v -= w
+		default:
+			return v, nil
+		}
+	}
+}
+
+func (p *arithParser) product() (float64, error) {
+	v, err := p.factor()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch p.peek() {
+		case '*':
+			p.pos++
+			w, err := p.factor()
+			if err != nil {
+				return 0, err
+			}
+			v *= w
+		case '/':
+			p.pos++
+			w, err := p.factor()
+			if err != nil {
+				return 0, err
+			}
+			if w == 0 {
+				return 0, errors.New("division by zero")
+			}
+			v /= w
+		default:
+			return v, nil
+		}
+	}
+}
+
+func (p *arithParser) factor() (float64, error) {
+	switch r := p.peek(); {
+	case r == '-':
+		p.pos++
+		v, err := p.factor()
+		return -v, err
+	case r == '(':
+		p.pos++
+		v, err := p.sum()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, errors.New("missing closing parenthesis")
+		}
+		p.pos++
+		return v, nil
+	case unicode.IsDigit(r) || r == '.':
+		start := p.pos
+		for p.pos < len(p.src) && (unicode.IsDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		return strconv.ParseFloat(string(p.src[start:p.pos]), 64)
+	case r == 0:
+		return 0, errors.New("unexpected end of expression")
+	default:
+		return 0, fmt.Errorf("unexpected %q at position %d", r, p.pos+1)
+	}
+}
@@ -0,0 +1,53 @@
+package simulation
+
+import (
+	"log"
+
+	"simarena/internal/llm"
+	"simarena/internal/models"
+)
+
+// callUsage returns the tokens a call used and their cost. If the server reported no
+// usage it is estimated from the messages and the reply. The price is looked up for the
+// model the server reports, or else for the requested model.
+func (e *Engine) callUsage(model string, messages []llm.ChatMessage, completion *llm.Completion) models.TokenUsage {
+	u, estimated := llm.EstimateUsage(messages, completion), true
+	if completion.Usage != nil {
+		u, estimated = *completion.Usage, false
+	}
+	usage := models.TokenUsage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.PromptTokens + u.CompletionTokens,
+		Estimated:        estimated,
+	}
+	if completion.Model != "" {
+		model = completion.Model
+	}
+	if price, ok, priced := e.price(model); ok {
+		usage.Cost = price.Cost(usage.PromptTokens, usage.CompletionTokens)
+	} else {
+		usage.Unpriced = priced
+	}
+	return usage
+}
+
+// price returns the price table's entry for model. priced reports whether the table has
+// any entries, so calls are only flagged as unpriced once prices are configured.
+func (e *Engine) price(model string) (price models.ModelPrice, ok, priced bool) {
+	prices, err := e.store.ListPrices()
+	if err != nil {
+		log.Printf("WARN: read model prices: %v", err)
+		return models.ModelPrice{}, false, false
+	}
+	price, ok = models.FindPrice(prices, model)
+	return price, ok, len(prices) > 0
+}
+
+// addStepUsage adds usage to the step's count.
+func addStepUsage(step *models.Step, usage models.TokenUsage) {
+	if step.Usage == nil {
+		step.Usage = &models.TokenUsage{}
+	}
+	step.Usage.Add(usage)
+}
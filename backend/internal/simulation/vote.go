@@ -0,0 +1,265 @@
+package simulation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"simarena/internal/llm"
+	"simarena/internal/models"
+)
+
+// RequestVote queues a vote for a running simulation. It is held after the current round,
+// or refused with ErrTooLate once the last round's votes have been held.
+func (e *Engine) RequestVote(simID string, vote models.VoteConfig) error {
+	c, err := e.control(simID)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.votesClosed {
+		return ErrTooLate
+	}
+	c.votes = append(c.votes, vote)
+	return nil
+}
+
+// scheduledVotes returns the simulation's votes scheduled after the given round.
+func scheduledVotes(sim *models.Simulation, round int) []models.VoteConfig {
+	var votes []models.VoteConfig
+	for _, v := range sim.Votes {
+		if v.Round == round {
+			votes = append(votes, v)
+		}
+	}
+	return votes
+}
+
+// runVote has every agent cast a ballot, tallies them under the vote's rule and records
+// the result as a vote event in the transcript.
+func (e *Engine) runVote(ctx context.Context, sim *models.Simulation, cfg models.VoteConfig) {
+	if len(cfg.Options) == 0 {
+		cfg.Options = []string{"yes", "no"}
+	}
+	if cfg.Rule == "" {
+		cfg.Rule = models.VoteRuleMajority
+	}
+
+	voters := sim.PresentAgents(cfg.Round)
+	if cfg.Eliminate {
+		cfg.Options = candidateNames(voters)
+	}
+
+	vote := models.Vote{VoteConfig: cfg, Ballots: make([]models.Ballot, 0, len(voters))}
+	step := models.Step{
+		Round:     cfg.Round,
+		Kind:      models.StepKindVote,
+		AgentName: voteEventName(sim),
+	}
+	for _, agent := range voters {
+		vote.Ballots = append(vote.Ballots, e.castBallot(ctx, sim, agent, cfg, &step))
+	}
+	vote.Tally, vote.Outcome = tallyVotes(cfg, vote.Ballots)
+
+	step.Content = formatVote(sim, &vote)
+	step.Vote = &vote
+	step.Timestamp = time.Now()
+	e.appendStep(sim, step)
+
+	if cfg.Eliminate && vote.Outcome != "" {
+		for _, agent := range voters {
+			if agent.Name == vote.Outcome {
+				scheduleDeparture(sim, agent.ID, cfg.Round+1, eliminationReason(sim))
+				break
+			}
+		}
+	}
+}
+
+// candidateNames returns the distinct names of the agents standing in an elimination vote.
+func candidateNames(agents []models.Agent) []string {
+	names := make([]string, 0, len(agents))
+	seen := make(map[string]bool, len(agents))
+	for _, a := range agents {
+		if !seen[a.Name] {
+			seen[a.Name] = true
+			names = append(names, a.Name)
+		}
+	}
+	return names
+}
+
+func eliminationReason(sim *models.Simulation) string {
+	if sim.Language == "ru" {
+		return "исключён голосованием"
+	}
+	return "eliminated by vote"
+}
+
+// castBallot asks one agent for its vote, re-prompting once on an invalid reply.
+// An agent that still gives no valid choice, or whose call fails, abstains. The calls'
+// tokens are added to the vote step's usage.
+func (e *Engine) castBallot(ctx context.Context, sim *models.Simulation, agent models.Agent, cfg models.VoteConfig, step *models.Step) models.Ballot {
+	ballot := models.Ballot{
+		AgentID:   agent.ID,
+		AgentName: agent.Name,
+		Choice:    models.VoteAbstain,
+		Weight:    agent.VoteWeight,
+	}
+	if ballot.Weight <= 0 {
+		ballot.Weight = 1
+	}
+
+	messages := BuildVoteMessages(sim, agent, cfg)
+	opts := llm.Options{
+		Model:           sim.Model,
+		Sampling:        toLLMSampling(sim.Sampling.Merge(agent.Sampling)),
+		ProviderOptions: sim.ModelOptions,
+	}
+	for attempt := 1; attempt <= 2; attempt++ {
+		completion, err := e.provider.ChatCompletion(ctx, messages, opts)
+		if err != nil {
+			log.Printf("ERROR: simulation %s vote: agent %s failed: %v", sim.ID, agent.Name, err)
+			return ballot
+		}
+		addStepUsage(step, e.callUsage(opts.Model, messages, completion))
+
+		choice, rationale, err := parseBallot(completion.Content, cfg.Options)
+		if err == nil {
+			ballot.Choice = choice
+			ballot.Rationale = e.guardRationale(ctx, sim, agent, rationale, step)
+			return ballot
+		}
+		messages = append(messages, completion.Message(), BuildRetryMessage(sim, err.Error(), false))
+	}
+	log.Printf("WARN: simulation %s vote: agent %s gave no valid ballot, abstaining", sim.ID, agent.Name)
+	return ballot
+}
+
+// guardRationale runs the simulation's guardrails over a ballot's rationale and returns
+// it redacted, or empty if a filter rejects it. Outcomes other than a pass and the usage
+// of moderation are recorded on the vote step.
+func (e *Engine) guardRationale(ctx context.Context, sim *models.Simulation, agent models.Agent, rationale string, step *models.Step) string {
+	if sim.Guardrails == nil || rationale == "" {
+		return rationale
+	}
+	r := models.Step{Content: rationale}
+	filter, _ := e.guardStep(ctx, sim, &r, 1)
+	if r.Usage != nil {
+		addStepUsage(step, *r.Usage)
+	}
+	for _, outcome := range r.Guardrails {
+		if outcome.Action != models.GuardPass {
+			outcome.Reason = agent.Name + ": " + outcome.Reason
+			step.Guardrails = append(step.Guardrails, outcome)
+		}
+	}
+	if filter != "" {
+		return ""
+	}
+	return r.Content
+}
+
+// parseBallot extracts the chosen option and rationale from a ballot reply.
+// The choice is matched case-insensitively and returned in its canonical spelling.
+func parseBallot(content string, options []string) (string, string, error) {
+	var parsed struct {
+		Choice    string `json:"choice"`
+		Rationale string `json:"rationale"`
+	}
+	if err := json.Unmarshal([]byte(extractJSONObject(content)), &parsed); err != nil {
+		return "", "", fmt.Errorf("invalid JSON: %v", err)
+	}
+	for _, opt := range options {
+		if strings.EqualFold(strings.TrimSpace(parsed.Choice), opt) {
+			return opt, parsed.Rationale, nil
+		}
+	}
+	return "", "", fmt.Errorf("%q is not one of the options %s", parsed.Choice, strings.Join(options, ", "))
+}
+
+// tallyVotes counts the ballots per option and decides the outcome under the vote's rule.
+// Abstentions count towards no option and are excluded from the total.
+func tallyVotes(cfg models.VoteConfig, ballots []models.Ballot) ([]models.OptionTally, string) {
+	tally := make([]models.OptionTally, len(cfg.Options))
+	for i, opt := range cfg.Options {
+		tally[i].Option = opt
+	}
+
+	var total float64
+	for _, b := range ballots {
+		if b.Choice == models.VoteAbstain {
+			continue
+		}
+		weight := 1.0
+		if cfg.Rule == models.VoteRuleWeighted {
+			weight = b.Weight
+		}
+		for i := range tally {
+			if tally[i].Option == b.Choice {
+				tally[i].Votes += weight
+			}
+		}
+		total += weight
+	}
+	if total == 0 {
+		return tally, ""
+	}
+
+	for _, t := range tally {
+		switch cfg.Rule {
+		case models.VoteRuleUnanimity:
+			if t.Votes == total {
+				return tally, t.Option
+			}
+		default:
+			if t.Votes > total/2 {
+				return tally, t.Option
+			}
+		}
+	}
+	return tally, ""
+}
+
+func voteEventName(sim *models.Simulation) string {
+	if sim.Language == "ru" {
+		return "Голосование"
+	}
+	return "Vote"
+}
+
+// formatVote renders a vote result as transcript text that agents and the summary can read.
+func formatVote(sim *models.Simulation, vote *models.Vote) string {
+	ru := sim.Language == "ru"
+
+	counts := make([]string, 0, len(vote.Tally))
+	for _, t := range vote.Tally {
+		counts = append(counts, fmt.Sprintf("%s %g", t.Option, t.Votes))
+	}
+
+	var b strings.Builder
+	outcome := vote.Outcome
+	if ru {
+		if outcome == "" {
+			outcome = "решение не принято"
+		}
+		b.WriteString(fmt.Sprintf("Голосование по предложению «%s» (правило: %s): %s. Итог: %s.\n", vote.Proposal, vote.Rule, strings.Join(counts, ", "), outcome))
+	} else {
+		if outcome == "" {
+			outcome = "no decision"
+		}
+		b.WriteString(fmt.Sprintf("Vote on the proposal \"%s\" (rule: %s): %s. Outcome: %s.\n", vote.Proposal, vote.Rule, strings.Join(counts, ", "), outcome))
+	}
+	for _, ballot := range vote.Ballots {
+		b.WriteString(fmt.Sprintf("- %s: %s", ballot.AgentName, ballot.Choice))
+		if ballot.Rationale != "" {
+			b.WriteString(" — " + truncate(ballot.Rationale, 300))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"fmt"
+
+	"simarena/internal/models"
+)
+
+// ListBatches returns all batches.
+func (s *JSONStore) ListBatches() ([]models.Batch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readFile[models.Batch](s.batchesPath)
+}
+
+// GetBatch returns a single batch by ID.
+func (s *JSONStore) GetBatch(id string) (*models.Batch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batches, err := readFile[models.Batch](s.batchesPath)
+	if err != nil {
+		return nil, err
+	}
+	for i := range batches {
+		if batches[i].ID == id {
+			return &batches[i], nil
+		}
+	}
+	return nil, fmt.Errorf("batch %s not found", id)
+}
+
+// CreateBatch adds a new batch to the store.
+func (s *JSONStore) CreateBatch(batch models.Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batches, err := readFile[models.Batch](s.batchesPath)
+	if err != nil {
+		return err
+	}
+	batches = append(batches, batch)
+	return writeFile(s.batchesPath, batches)
+}
+
+// UpdateBatch replaces a batch in the store by ID.
+func (s *JSONStore) UpdateBatch(batch models.Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batches, err := readFile[models.Batch](s.batchesPath)
+	if err != nil {
+		return err
+	}
+	for i := range batches {
+		if batches[i].ID == batch.ID {
+			batches[i] = batch
+			return writeFile(s.batchesPath, batches)
+		}
+	}
+	return fmt.Errorf("batch %s not found", batch.ID)
+}
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"simarena/internal/llm"
+)
+
+// cassettePath returns the file holding a simulation's recorded LLM interactions.
+func (s *JSONStore) cassettePath(simID string) string {
+	return filepath.Join(s.cassettesDir, simID+".json")
+}
+
+// SaveCassette stores the recorded LLM interactions of a simulation.
+func (s *JSONStore) SaveCassette(simID string, interactions []llm.Interaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.cassettesDir, 0755); err != nil {
+		return fmt.Errorf("create cassettes dir: %w", err)
+	}
+	return writeFile(s.cassettePath(simID), interactions)
+}
+
+// GetCassette returns the recorded LLM interactions of a simulation.
+func (s *JSONStore) GetCassette(simID string) ([]llm.Interaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.cassettePath(simID)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("cassette for %s not found", simID)
+	}
+	return readFile[llm.Interaction](path)
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"fmt"
+
+	"simarena/internal/models"
+)
+
+// ListExperiments returns all experiments.
+func (s *JSONStore) ListExperiments() ([]models.Experiment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readFile[models.Experiment](s.experimentsPath)
+}
+
+// GetExperiment returns a single experiment by ID.
+func (s *JSONStore) GetExperiment(id string) (*models.Experiment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	experiments, err := readFile[models.Experiment](s.experimentsPath)
+	if err != nil {
+		return nil, err
+	}
+	for i := range experiments {
+		if experiments[i].ID == id {
+			return &experiments[i], nil
+		}
+	}
+	return nil, fmt.Errorf("experiment %s not found", id)
+}
+
+// CreateExperiment adds a new experiment to the store.
+func (s *JSONStore) CreateExperiment(experiment models.Experiment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	experiments, err := readFile[models.Experiment](s.experimentsPath)
+	if err != nil {
+		return err
+	}
+	experiments = append(experiments, experiment)
+	return writeFile(s.experimentsPath, experiments)
+}
+
+// UpdateExperiment replaces a experiment in the store by ID.
+func (s *JSONStore) UpdateExperiment(experiment models.Experiment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	experiments, err := readFile[models.Experiment](s.experimentsPath)
+	if err != nil {
+		return err
+	}
+	for i := range experiments {
+		if experiments[i].ID == experiment.ID {
+			experiments[i] = experiment
+			return writeFile(s.experimentsPath, experiments)
+		}
+	}
+	return fmt.Errorf("experiment %s not found", experiment.ID)
+}
@@ -10,10 +10,15 @@ import (
 	"simarena/internal/models"
 )
 
-// JSONStore is a file-based JSON storage for simulations.
+// JSONStore is a file-based JSON storage for simulations and the records grouping them.
 type JSONStore struct {
-	mu       sync.Mutex
-	filePath string
+	mu              sync.Mutex
+	filePath        string
+	batchesPath     string
+	experimentsPath string
+	rubricsPath     string
+	pricesPath      string
+	cassettesDir    string
 }
 
 // NewJSONStore creates a new JSON file store at the given directory.
@@ -22,39 +27,54 @@ func NewJSONStore(dataDir string) (*JSONStore, error) {
 		return nil, fmt.Errorf("create data dir: %w", err)
 	}
 	return &JSONStore{
-		filePath: filepath.Join(dataDir, "simulations.json"),
+		filePath:        filepath.Join(dataDir, "simulations.json"),
+		batchesPath:     filepath.Join(dataDir, "batches.json"),
+		experimentsPath: filepath.Join(dataDir, "experiments.json"),
+		rubricsPath:     filepath.Join(dataDir, "rubrics.json"),
+		pricesPath:      filepath.Join(dataDir, "prices.json"),
+		cassettesDir:    filepath.Join(dataDir, "cassettes"),
 	}, nil
 }
 
-func (s *JSONStore) readAll() ([]models.Simulation, error) {
-	data, err := os.ReadFile(s.filePath)
+// readFile loads a JSON array of records from path. A missing or empty file yields no records.
+func readFile[T any](path string) ([]T, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return []models.Simulation{}, nil
+			return []T{}, nil
 		}
 		return nil, fmt.Errorf("read file: %w", err)
 	}
 	if len(data) == 0 {
-		return []models.Simulation{}, nil
+		return []T{}, nil
 	}
-	var sims []models.Simulation
-	if err := json.Unmarshal(data, &sims); err != nil {
+	var records []T
+	if err := json.Unmarshal(data, &records); err != nil {
 		return nil, fmt.Errorf("unmarshal: %w", err)
 	}
-	return sims, nil
+	return records, nil
 }
 
-func (s *JSONStore) writeAll(sims []models.Simulation) error {
-	data, err := json.MarshalIndent(sims, "", "  ")
+// writeFile stores records as an indented JSON array at path.
+func writeFile[T any](path string, records []T) error {
+	data, err := json.MarshalIndent(records, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal: %w", err)
 	}
-	if err := os.WriteFile(s.filePath, data, 0644); err != nil {
+	if err := os.WriteFile(path, data, 0644); err != nil {
 		return fmt.Errorf("write file: %w", err)
 	}
 	return nil
 }
 
+func (s *JSONStore) readAll() ([]models.Simulation, error) {
+	return readFile[models.Simulation](s.filePath)
+}
+
+func (s *JSONStore) writeAll(sims []models.Simulation) error {
+	return writeFile(s.filePath, sims)
+}
+
 // List returns all simulations.
 func (s *JSONStore) List() ([]models.Simulation, error) {
 	s.mu.Lock()
@@ -127,5 +147,8 @@ func (s *JSONStore) Delete(id string) error {
 	if !found {
 		return fmt.Errorf("simulation %s not found", id)
 	}
+	if err := os.Remove(s.cassettePath(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove cassette: %w", err)
+	}
 	return s.writeAll(filtered)
 }
@@ -0,0 +1,17 @@
+package storage
+
+import "simarena/internal/models"
+
+// ListPrices returns the model price table.
+func (s *JSONStore) ListPrices() ([]models.ModelPrice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readFile[models.ModelPrice](s.pricesPath)
+}
+
+// SavePrices replaces the model price table.
+func (s *JSONStore) SavePrices(prices []models.ModelPrice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeFile(s.pricesPath, prices)
+}
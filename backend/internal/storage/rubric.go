@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"fmt"
+
+	"simarena/internal/models"
+)
+
+// ListRubrics returns all rubrics.
+func (s *JSONStore) ListRubrics() ([]models.Rubric, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readFile[models.Rubric](s.rubricsPath)
+}
+
+// GetRubric returns a single rubric by ID.
+func (s *JSONStore) GetRubric(id string) (*models.Rubric, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rubrics, err := readFile[models.Rubric](s.rubricsPath)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rubrics {
+		if rubrics[i].ID == id {
+			return &rubrics[i], nil
+		}
+	}
+	return nil, fmt.Errorf("rubric %s not found", id)
+}
+
+// CreateRubric adds a new rubric to the store.
+func (s *JSONStore) CreateRubric(rubric models.Rubric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rubrics, err := readFile[models.Rubric](s.rubricsPath)
+	if err != nil {
+		return err
+	}
+	rubrics = append(rubrics, rubric)
+	return writeFile(s.rubricsPath, rubrics)
+}
+
+// DeleteRubric removes a rubric by ID.
+func (s *JSONStore) DeleteRubric(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rubrics, err := readFile[models.Rubric](s.rubricsPath)
+	if err != nil {
+		return err
+	}
+	filtered := make([]models.Rubric, 0, len(rubrics))
+	found := false
+	for _, rubric := range rubrics {
+		if rubric.ID == id {
+			found = true
+			continue
+		}
+		filtered = append(filtered, rubric)
+	}
+	if !found {
+		return fmt.Errorf("rubric %s not found", id)
+	}
+	return writeFile(s.rubricsPath, filtered)
+}
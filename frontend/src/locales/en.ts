@@ -44,6 +44,7 @@ export default {
     running: 'Running',
     completed: 'Completed',
     failed: 'Failed',
+    budget_exceeded: 'Budget exceeded',
   },
   lang: {
     en: 'EN',
@@ -44,6 +44,7 @@ export default {
     running: 'Выполняется',
     completed: 'Завершена',
     failed: 'Ошибка',
+    budget_exceeded: 'Бюджет исчерпан',
   },
   lang: {
     en: 'EN',
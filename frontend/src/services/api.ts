@@ -1,4 +1,4 @@
-import type { Simulation, CreateSimulationRequest } from '@/types/simulation'
+import type { Simulation, CreateSimulationRequest, Batch, CreateBatchRequest, Comparison } from '@/types/simulation'
 
 const BASE_URL = '/api'
 
@@ -31,3 +31,35 @@ export async function deleteSimulation(id: string): Promise<void> {
   const res = await fetch(`${BASE_URL}/simulations/${id}`, { method: 'DELETE' })
   if (!res.ok) throw new Error(`HTTP ${res.status}`)
 }
+
+export async function compareSimulations(simulationIds: string[]): Promise<Comparison> {
+  const res = await fetch(`${BASE_URL}/simulations/compare`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ simulation_ids: simulationIds }),
+  })
+  if (!res.ok) {
+    const err = await res.json().catch(() => ({ error: 'Unknown error' }))
+    throw new Error(err.error || `HTTP ${res.status}`)
+  }
+  return res.json()
+}
+
+export async function createBatch(req: CreateBatchRequest): Promise<Batch> {
+  const res = await fetch(`${BASE_URL}/batches`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(req),
+  })
+  if (!res.ok) {
+    const err = await res.json().catch(() => ({ error: 'Unknown error' }))
+    throw new Error(err.error || `HTTP ${res.status}`)
+  }
+  return res.json()
+}
+
+export async function getBatch(id: string): Promise<Batch> {
+  const res = await fetch(`${BASE_URL}/batches/${id}`)
+  if (!res.ok) throw new Error(`HTTP ${res.status}`)
+  return res.json()
+}
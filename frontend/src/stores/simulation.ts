@@ -43,6 +43,10 @@ export const useSimulationStore = defineStore('simulation', () => {
         currentSimulation.value.final_result = step.content
         currentSimulation.value.status = 'completed'
         disconnectWebSocket()
+      } else if (step.kind === 'budget' && step.budget) {
+        // Budget warnings are not part of the transcript
+        const warnings = (currentSimulation.value.budget_warnings ??= [])
+        warnings.push(step.budget)
       } else {
         currentSimulation.value.steps.push(step)
       }
@@ -1,14 +1,181 @@
+export interface SamplingParams {
+  temperature?: number
+  top_p?: number
+  seed?: number
+  presence_penalty?: number
+  frequency_penalty?: number
+  stop?: string[]
+}
+
 export interface Agent {
   id: string
   name: string
   role: string
+  sampling?: SamplingParams
+  vote_weight?: number
+  join_round?: number
+  leave_round?: number
+  left_reason?: string
+  added?: boolean
+  removed?: boolean
+}
+
+export interface StructuredTurn {
+  analysis: string
+  decision: string
+  action: string
+  message_to_others: string
+}
+
+export interface ToolCall {
+  name: string
+  arguments: string
+  result?: string
+  error?: string
+}
+
+export interface VoteConfig {
+  round: number
+  proposal: string
+  options?: string[]
+  rule?: 'majority' | 'unanimity' | 'weighted'
+  eliminate?: boolean
+}
+
+export interface Ballot {
+  agent_id: string
+  agent_name: string
+  choice: string
+  weight: number
+  rationale: string
+}
+
+export interface Vote extends VoteConfig {
+  ballots: Ballot[]
+  tally: { option: string; votes: number }[]
+  outcome: string
+}
+
+export interface GuardrailConfig {
+  blocklist?: string[]
+  block_action?: 'redact' | 'regenerate'
+  pii?: boolean
+  max_chars?: number
+  moderation?: boolean
+}
+
+export interface GuardrailOutcome {
+  filter: 'blocklist' | 'pii' | 'length' | 'moderation'
+  action: 'pass' | 'redact' | 'regenerate'
+  reason?: string
+  attempt: number
+}
+
+export interface TokenUsage {
+  prompt_tokens: number
+  completion_tokens: number
+  total_tokens: number
+  estimated?: boolean
+  cost?: number
+  unpriced?: boolean
+}
+
+export interface ModelPrice {
+  model: string
+  input: number
+  output: number
+}
+
+export interface RateLimits {
+  requests_per_minute?: number
+  tokens_per_minute?: number
+  max_streams?: number
+}
+
+export interface RateLimitStatus {
+  endpoint: string
+  model: string
+  limits: RateLimits
+  requests: number
+  tokens: number
+  streams: number
+  waiting: number
+  saturation: number
+}
+
+export interface EndpointStatus {
+  name: string
+  models?: string[]
+  in_flight: number
+  failures: number
+  ejected: boolean
+  open_until?: string
+}
+
+export interface Budget {
+  max_tokens?: number
+  max_cost?: number
+  warn_at?: number[]
+  summary_reserve?: number
+}
+
+export interface BudgetWarning {
+  threshold: number
+  spent: number
+  limit: number
+  projected: number
+  timestamp: string
+}
+
+export interface StepVersion {
+  content: string
+  structured?: StructuredTurn
+  tool_calls?: ToolCall[]
+  guardrails?: GuardrailOutcome[]
+  usage?: TokenUsage
+  timestamp: string
+}
+
+export interface FailurePolicy {
+  retries?: number
+  backoff_seconds?: number
+  on_failure?: 'fail' | 'skip' | 'fallback'
+  fallback_model?: string
+}
+
+export interface TurnFailure {
+  attempt: number
+  model?: string
+  error: string
+  status_code?: number
+  timestamp: string
+}
+
+export interface StepEdit {
+  original: string
+  edited: string
+  editor: string
+  edited_at: string
 }
 
 export interface Step {
   round: number
+  kind?: 'vote' | 'roster' | 'skipped' | 'error' | 'budget'
   agent_id: string
   agent_name: string
   content: string
+  structured?: StructuredTurn
+  tool_calls?: ToolCall[]
+  vote?: Vote
+  children?: string[]
+  guardrails?: GuardrailOutcome[]
+  stale?: boolean
+  alternatives?: StepVersion[]
+  edits?: StepEdit[]
+  failures?: TurnFailure[]
+  model?: string
+  usage?: TokenUsage
+  budget?: BudgetWarning
   timestamp: string
 }
 
@@ -21,15 +188,41 @@ export interface Simulation {
   agents: Agent[]
   language: 'en' | 'ru'
   depth: 'shallow' | 'medium' | 'deep'
-  status: 'running' | 'completed' | 'failed'
+  model?: string
+  structured?: boolean
+  tools?: string[]
+  votes?: VoteConfig[]
+  sampling: SamplingParams
+  status: 'running' | 'completed' | 'failed' | 'budget_exceeded'
   steps: Step[]
   final_result?: string
+  rubric_id?: string
+  evaluation?: Evaluation
+  guardrails?: GuardrailConfig
+  failure_policy?: FailurePolicy
+  model_options?: Record<string, unknown>
+  record?: boolean
+  replay_of?: string
+  divergences?: number[]
+  usage: TokenUsage
+  budget?: Budget
+  budget_warnings?: BudgetWarning[]
+  parent_id?: string
+  parent_agent_id?: string
+  parent_round?: number
+  level?: number
+  batch_id?: string
+  experiment_id?: string
   created_at: string
 }
 
 export interface AgentRequest {
   name: string
   role: string
+  sampling?: SamplingParams
+  vote_weight?: number
+  join_round?: number
+  leave_round?: number
 }
 
 export interface CreateSimulationRequest {
@@ -40,4 +233,166 @@ export interface CreateSimulationRequest {
   agents: AgentRequest[]
   language: 'en' | 'ru'
   depth: 'shallow' | 'medium' | 'deep'
+  model?: string
+  structured?: boolean
+  tools?: string[]
+  votes?: VoteConfig[]
+  sampling?: SamplingParams
+  rubric_id?: string
+  record?: boolean
+  guardrails?: GuardrailConfig
+  failure_policy?: FailurePolicy
+  model_options?: Record<string, unknown>
+  budget?: Budget
+}
+
+export interface OutcomeGroup {
+  label: string
+  count: number
+  simulation_ids: string[]
+}
+
+export interface Outlier {
+  simulation_id: string
+  reason: string
+}
+
+export interface BatchReport {
+  outcomes: OutcomeGroup[]
+  turning_points: string[]
+  outliers: Outlier[]
+  summary: string
+}
+
+export interface Batch {
+  id: string
+  description: string
+  runs: number
+  base_seed: number
+  simulation_ids: string[]
+  status: 'running' | 'completed' | 'failed'
+  completed: number
+  failed: number
+  report?: BatchReport
+  usage: TokenUsage
+  created_at: string
+}
+
+export interface CreateBatchRequest {
+  simulation: CreateSimulationRequest
+  runs: number
+  base_seed?: number
+}
+
+export interface AgentsVariant {
+  label: string
+  agents: AgentRequest[]
+}
+
+export interface PreconditionsVariant {
+  label: string
+  preconditions: string
+}
+
+export interface ExperimentAxes {
+  depth?: Array<'shallow' | 'medium' | 'deep'>
+  model?: string[]
+  agents?: AgentsVariant[]
+  preconditions?: PreconditionsVariant[]
+}
+
+export interface ExperimentCell {
+  simulation_id: string
+  depth?: string
+  model?: string
+  agents?: string
+  preconditions?: string
+}
+
+export interface Experiment {
+  id: string
+  name: string
+  base: CreateSimulationRequest
+  axes: ExperimentAxes
+  cells: ExperimentCell[]
+  status: 'running' | 'completed' | 'failed'
+  completed: number
+  failed: number
+  created_at: string
+}
+
+export interface ExperimentResult extends ExperimentCell {
+  status: Simulation['status'] | 'deleted'
+  steps: number
+  score?: number
+  final_result?: string
+}
+
+export interface AgentStats {
+  name: string
+  steps: number
+  avg_chars: number
+  avg_tokens: number
+  total_chars: number
+}
+
+export interface ComparisonRun {
+  simulation_id: string
+  label: string
+  status: Simulation['status']
+  model?: string
+  depth: Simulation['depth']
+  rounds: number
+  steps: number
+  tokens: number
+  agents: AgentStats[]
+}
+
+export interface AlignedRound {
+  round: number
+  steps: Step[][]
+}
+
+export interface Comparison {
+  runs: ComparisonRun[]
+  rounds: AlignedRound[]
+  analysis: string
+  usage: TokenUsage
+}
+
+export interface Criterion {
+  name: string
+  description: string
+  scale_min: number
+  scale_max: number
+}
+
+export interface Rubric {
+  id: string
+  name: string
+  criteria: Criterion[]
+  judge_model?: string
+  created_at: string
+}
+
+export interface CriterionScore {
+  criterion: string
+  score: number
+  rationale: string
+}
+
+export interface AgentEvaluation {
+  agent_id: string
+  agent_name: string
+  scores: CriterionScore[]
+}
+
+export interface Evaluation {
+  rubric_id: string
+  judge_model?: string
+  agents: AgentEvaluation[]
+  overall: CriterionScore[]
+  score: number
+  usage: TokenUsage
+  created_at: string
 }